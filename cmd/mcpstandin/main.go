@@ -0,0 +1,122 @@
+// Command mcpstandin is a minimal MCP server speaking stdio, for trying out
+// MCP tools without a real server:
+//
+//	{"mcpServers": {"standin": {"command": "go", "args": ["run", "./cmd/mcpstandin"]}}}
+//
+// It offers an echo tool and an add tool, and a fail tool that always
+// reports an error. Like a real server, it only lists and calls tools once
+// the client has sent notifications/initialized.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+var tools = []map[string]interface{}{
+	{
+		"name":        "echo",
+		"description": "Repeats the given text back",
+		"inputSchema": json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}`),
+	},
+	{
+		"name":        "add",
+		"description": "Adds two numbers",
+		"inputSchema": json.RawMessage(`{"type":"object","properties":{"a":{"type":"number"},"b":{"type":"number"}},"required":["a","b"]}`),
+	},
+	{
+		"name":        "fail",
+		"description": "Always fails, for testing error handling",
+		"inputSchema": json.RawMessage(`{"type":"object","properties":{}}`),
+	},
+}
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	initialized := false
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid request: %v\n", err)
+			continue
+		}
+		if req.ID == nil {
+			// Notifications need no reply
+			if req.Method == "notifications/initialized" {
+				initialized = true
+			}
+			continue
+		}
+
+		var result interface{}
+		var err error
+		if strings.HasPrefix(req.Method, "tools/") && !initialized {
+			err = fmt.Errorf("%s before notifications/initialized", req.Method)
+		} else {
+			result, err = handle(req)
+		}
+		reply := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if err != nil {
+			reply["error"] = map[string]interface{}{"code": -32601, "message": err.Error()}
+		} else {
+			reply["result"] = result
+		}
+		encoder.Encode(reply)
+	}
+}
+
+func handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "mcpstandin", "version": "1.0.0"},
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Text string  `json:"text"`
+				A    float64 `json:"a"`
+				B    float64 `json:"b"`
+			} `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		switch params.Name {
+		case "echo":
+			return textResult(params.Arguments.Text, false), nil
+		case "add":
+			return textResult(fmt.Sprint(params.Arguments.A+params.Arguments.B), false), nil
+		case "fail":
+			return textResult("the fail tool always fails", true), nil
+		default:
+			return nil, fmt.Errorf("unknown tool: %s", params.Name)
+		}
+	default:
+		return nil, fmt.Errorf("method not found: %s", req.Method)
+	}
+}
+
+func textResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
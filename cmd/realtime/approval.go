@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"os"
+	"realtime/pkg/approval"
+	"realtime/pkg/provider"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// keyApprover asks for the approval of tool calls on the terminal, one call
+// at a time, and takes the answer from the keyboard
+type keyApprover struct {
+	turn    chan struct{} // Held while a call is shown, so concurrent calls queue up
+	mu      sync.Mutex
+	pending chan bool // Receives the answer to the call shown, nil if none
+}
+
+func newKeyApprover() *keyApprover {
+	return &keyApprover{turn: make(chan struct{}, 1)}
+}
+
+// approve shows the pending action and waits for y or n
+func (a *keyApprover) approve(ctx context.Context, call provider.ToolCall) (approval.Decision, error) {
+	select {
+	case a.turn <- struct{}{}:
+	case <-ctx.Done():
+		return approval.Decision{}, ctx.Err()
+	}
+	defer func() { <-a.turn }()
+
+	answer := make(chan bool, 1)
+	a.mu.Lock()
+	a.pending = answer
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.pending = nil
+		a.mu.Unlock()
+	}()
+
+	log.Warnf("The assistant wants to run %s(%s). Press y to allow or n to deny", call.Name, call.Arguments)
+	select {
+	case approved := <-answer:
+		if approved {
+			log.Infof("Allowed %s", call.Name)
+		} else {
+			log.Infof("Denied %s", call.Name)
+		}
+		return approval.Decision{Approved: approved, By: "keyboard"}, nil
+	case <-ctx.Done():
+		log.Warnf("No answer, %s was denied", call.Name)
+		return approval.Decision{}, ctx.Err()
+	}
+}
+
+// handleKey answers the call shown with y or n, and reports whether the key was used
+func (a *keyApprover) handleKey(char rune) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending == nil {
+		return false
+	}
+	switch char {
+	case 'y', 'Y':
+		a.pending <- true
+	case 'n', 'N':
+		a.pending <- false
+	default:
+		return false
+	}
+	a.pending = nil
+	return true
+}
+
+// auditFile appends to a file that is only created once something is written
+type auditFile struct {
+	path string
+	once sync.Once
+	file *os.File
+	err  error
+}
+
+func (f *auditFile) Write(p []byte) (int, error) {
+	f.once.Do(func() {
+		f.file, f.err = os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	})
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.file.Write(p)
+}
+
+// registerTools declares tools, marking those named in confirm as requiring
+// confirmation and those named in async as long-running
+func registerTools(assistant provider.RealtimeProvider, tools []provider.Tool, confirm, async map[string]bool) {
+	for _, tool := range tools {
+		if confirm[tool.Name] {
+			tool.RequiresConfirmation = true
+		}
+		if async[tool.Name] {
+			tool.Async = true
+		}
+		if err := assistant.RegisterTool(tool); err != nil {
+			log.Fatalf("Failed to register tool %s: %v", tool.Name, err)
+		}
+	}
+}
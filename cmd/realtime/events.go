@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"realtime/pkg/openairealtime"
+	"realtime/pkg/provider"
+
+	"github.com/charmbracelet/log"
+)
+
+// instructionsUpdater is implemented by providers that can change their
+// instructions during a session
+type instructionsUpdater interface {
+	SetInstructions(instructions string) error
+}
+
+// latencyReporter is implemented by providers that measure the latency of each turn
+type latencyReporter interface {
+	LatencyReport() openairealtime.LatencyReport
+}
+
+// loadAgents reads a JSON array of agents
+func loadAgents(path string) ([]openairealtime.Agent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var agents []openairealtime.Agent
+	if err := json.Unmarshal(data, &agents); err != nil {
+		return nil, fmt.Errorf("invalid agents file %s: %w", path, err)
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents in %s", path)
+	}
+	return agents, nil
+}
+
+// logLatencyReport prints the latency percentiles of the session
+func logLatencyReport(report openairealtime.LatencyReport) {
+	if report.Turns == 0 {
+		return
+	}
+	log.Info("Latency from end of speech to hearing the response", "turns", report.Turns,
+		"p50", report.Total.P50, "p90", report.Total.P90, "p99", report.Total.P99)
+	log.Info("Latency breakdown (p50/p90)",
+		"commit", fmt.Sprintf("%v/%v", report.Commit.P50, report.Commit.P90),
+		"creation", fmt.Sprintf("%v/%v", report.Creation.P50, report.Creation.P90),
+		"first_delta", fmt.Sprintf("%v/%v", report.FirstDelta.P50, report.FirstDelta.P90),
+		"playout", fmt.Sprintf("%v/%v", report.Playout.P50, report.Playout.P90),
+	)
+}
+
+// eventLogger prints the conversation as it happens through logger, so
+// transcripts are redacted like every other log line
+func eventLogger(logger *slog.Logger) provider.EventHandler {
+	return func(event provider.Event) {
+		switch event.Type {
+		case provider.InputTranscriptDelta:
+			logger.Debug(fmt.Sprintf("You (partial): %s", event.Text))
+		case provider.InputTranscript:
+			logger.Info(fmt.Sprintf("You: %s", event.Text))
+		case provider.InputTranscriptFailed:
+			logger.Warn(fmt.Sprintf("Failed to transcribe your speech: %s", event.Text))
+		case provider.Transcript:
+			logger.Info(fmt.Sprintf("Assistant: %s", event.Text))
+		case provider.ToolCalled:
+			logger.Info(fmt.Sprintf("Assistant called %s(%s)", event.ToolCall.Name, event.ToolCall.Arguments))
+		case provider.GuardrailTripped:
+			logger.Warn(fmt.Sprintf("Guardrail tripped: %s", event.Text))
+		case provider.Handoff:
+			logger.Info(fmt.Sprintf("Handed off to the %s agent", event.Text))
+		case provider.ResponseInterrupted:
+			logger.Debug(fmt.Sprintf("Response %s was interrupted", event.ResponseID))
+		case provider.Error:
+			logger.Error(fmt.Sprintf("Assistant error: %s", event.Text))
+		}
+	}
+}
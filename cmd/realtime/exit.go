@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	exitHooks   []func()
+	exitHooksMu sync.Mutex
+)
+
+// atExit registers a function to run before the app exits, e.g. to flush telemetry
+func atExit(hook func()) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// exit runs the exit hooks, most recently registered first, and exits
+func exit(code int) {
+	exitHooksMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitHooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	os.Exit(code)
+}
@@ -1,20 +1,144 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log/slog"
 	"os"
+	"realtime/pkg/approval"
+	"realtime/pkg/audioformat"
 	"realtime/pkg/audioinput"
 	"realtime/pkg/audiooutput"
+	"realtime/pkg/geminilive"
+	"realtime/pkg/guardrail"
+	"realtime/pkg/instructions"
+	"realtime/pkg/logging"
+	"realtime/pkg/mcp"
 	"realtime/pkg/openairealtime"
+	"realtime/pkg/pii"
+	"realtime/pkg/pipeline"
+	"realtime/pkg/provider"
+	"realtime/pkg/tracing"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
 
 func main() {
+	backend := flag.String("backend", "openai",
+		"Speech-to-speech backend: openai, gemini (uses GEMINI_API_KEY) or pipeline (local STT -> LLM -> TTS servers)")
+	openaiProtocol := flag.String("openai-protocol", string(openairealtime.ProtocolBeta),
+		"Realtime API protocol of the openai backend: beta or ga")
+	openaiModel := flag.String("openai-model", "",
+		"Model of the openai backend (default gpt-4o-realtime-preview-2024-12-17 with the beta protocol, gpt-realtime with ga)")
+	pipelineURL := flag.String("pipeline-url", "http://localhost:8080/v1", "OpenAI-compatible API base URL for the pipeline backend")
+	sttURL := flag.String("stt-url", "", "Transcription server base URL for the pipeline backend, defaults to -pipeline-url")
+	llmURL := flag.String("llm-url", "", "Chat completion server base URL for the pipeline backend, defaults to -pipeline-url")
+	ttsURL := flag.String("tts-url", "", "Speech server base URL for the pipeline backend, defaults to -pipeline-url")
+	llmModel := flag.String("llm-model", "", "Chat model for the pipeline backend")
+	duplex := flag.String("duplex", string(openairealtime.HalfDuplex),
+		"Microphone duplex policy while the assistant talks: half, full (headsets) or echo-gated")
+	manualTurns := flag.Bool("manual-turns", false,
+		"End turns when the spacebar is released instead of using server voice activity detection")
+	eagerness := flag.String("eagerness", "",
+		"Use semantic voice activity detection with this eagerness: low (patient), medium, high (eager) or auto")
+	format := flag.String("audio-format", string(audioformat.PCM16),
+		"Session audio format: pcm16, g711_ulaw or g711_alaw")
+	transcriptionModel := flag.String("transcription-model", "",
+		"Model transcribing your speech, e.g. gpt-4o-transcribe (default whisper-1)")
+	transcriptionLanguage := flag.String("transcription-language", "", "ISO-639-1 code of the language you speak, e.g. en")
+	transcriptionPrompt := flag.String("transcription-prompt", "", "Vocabulary or context to bias the transcription of your speech")
+	mic := flag.String("mic", "auto",
+		"Microphone in use, picks the server noise reduction: headset (near field), laptop (far field), off, or auto to detect a headset by its device name")
+	mcpConfig := flag.String("mcp-config", "",
+		"JSON file listing MCP servers whose tools the assistant can call, in the mcpServers format")
+	builtinTools := flag.String("tools", "",
+		"Comma-separated built-in tools to offer the assistant: clock, timers and calculator")
+	toolsDir := flag.String("tools-dir", "", "Let the assistant read files under this directory")
+	toolsCommands := flag.String("tools-commands", "", "Comma-separated programs the assistant may run, e.g. git,ls")
+	toolsHosts := flag.String("tools-hosts", "", "Comma-separated hosts the assistant may fetch pages from with HTTP GET")
+	confirmTools := flag.String("confirm-tools", "",
+		"Comma-separated tools, e.g. of MCP servers, that wait for you to press y before they run. run_command always does")
+	asyncTools := flag.String("async-tools", "",
+		"Comma-separated long-running tools that run in the background while the conversation goes on")
+	auditLog := flag.String("audit-log", "tool-approvals.jsonl", "File every decision on a tool call awaiting confirmation is appended to")
+	agentsFile := flag.String("agents", "",
+		"JSON file of agents the openai backend hands off between, e.g. prompts/agents.json")
+	guardrailWords := flag.String("guardrail-words", "",
+		"Comma-separated words or phrases that cut off the assistant when it says them, and are removed when you say them")
+	guardrailReply := flag.String("guardrail-reply", "Sorry, I can't help with that.",
+		"What the assistant says instead of a response cut off by a guardrail, empty to say nothing")
+	redactPII := flag.Bool("redact-pii", true,
+		"Redact personal data, e.g. emails and card numbers, in logs, transcripts and the audit log")
+	redactModelPII := flag.Bool("redact-pii-model", false,
+		"Also redact personal data in text and tool outputs sent to the model. Your speech is sent as it is")
+	piiDetectors := flag.String("pii-detectors", "",
+		"Comma-separated built-in PII detectors: email, card, ssn, iban, phone and address (default all)")
+	piiPatternFlags := piiPatterns{}
+	flag.Var(piiPatternFlags, "pii-pattern", "Extra PII detector as name=regexp, e.g. account=ACC-\\d{8}, can be repeated")
+	promptFile := flag.String("prompt", "",
+		"Instructions template file, e.g. prompts/default.tmpl, reloaded when it changes")
+	userName := flag.String("user", "", "Name of the user, available to prompt templates as {{.UserName}}")
+	locale := flag.String("locale", os.Getenv("LANG"), "Locale of the user, available to prompt templates as {{.Locale}}")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics on this address at /metrics, e.g. :9090")
+	otlpEndpoint := flag.String("otlp-endpoint", "",
+		"Export a trace of each conversation turn over OTLP/HTTP to this collector, e.g. http://localhost:4318")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	flag.Parse()
+
+	// Every package logs through the same logger, with secrets and audio payloads redacted
+	level, err := log.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatalf("Invalid log level: %v", err)
+	}
+	log.SetDefault(log.NewWithOptions(os.Stderr, log.Options{
+		ReportCaller:    true,
+		ReportTimestamp: true,
+		Level:           level,
+	}))
+	redactor, err := newRedactor(*piiDetectors, piiPatternFlags)
+	if err != nil {
+		log.Fatalf("Invalid PII redaction: %v", err)
+	}
+	var logPII, modelPII *pii.Redactor
+	if *redactPII {
+		logPII = redactor
+	}
+	if *redactModelPII {
+		modelPII = redactor
+	}
+	logger := slog.New(logging.NewRedactingHandler(log.Default()).WithPII(logPII))
+
+	if *metricsAddr != "" {
+		go serveMetrics(*metricsAddr)
+	}
+
+	if *otlpEndpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), *otlpEndpoint)
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		atExit(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				log.Errorf("Failed to flush traces: %v", err)
+			}
+		})
+	}
+
+	audioFormat := audioformat.Format(*format)
+	if err := audioFormat.Validate(); err != nil {
+		log.Fatalf("Invalid audio format: %v", err)
+	}
+
 	// Get an audio input stream
 	audioInput, err := audioinput.Init(audioinput.Config{
 		Channels:        1,
 		SampleRate:      24000,
 		FramesPerBuffer: 1920,
+		Format:          audioFormat,
+		Logger:          logger,
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize audio input: %v", err)
@@ -26,11 +150,19 @@ func main() {
 	}
 	log.Printf("Listening for audio input")
 
+	deviceName, err := audioInput.DeviceName()
+	if err != nil {
+		log.Warnf("Failed to get the default input device: %v", err)
+	}
+	noiseReduction, err := noiseReductionFor(*mic, deviceName)
+	if err != nil {
+		log.Fatalf("Invalid microphone: %v", err)
+	}
+	log.Infof("Using input device %q with noise reduction %q", deviceName, noiseReduction)
+
 	// Mute the audio input by default
 	audioInput.Mute()
 
-	go UnmuteOnSpacebar(audioInput)
-
 	// Write audio to a file for testing
 	// for {
 	// 	audioData := <-inputchan
@@ -41,32 +173,231 @@ func main() {
 		Channels:        1,
 		SampleRate:      24000,
 		FramesPerBuffer: 480,
+		Format:          audioFormat,
+		Logger:          logger,
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize audio output: %v", err)
 	}
 
-	outputchan := make(chan []byte)
+	outputchan := make(chan provider.AudioChunk)
 
 	err = audioOutput.Play(outputchan)
 	if err != nil {
 		log.Fatalf("Failed to play audio: %v", err)
 	}
 
-	// Get an OpenAI Realtime client
-	openaiRealtime, err := openairealtime.GetOpenAIRealtimeClient(openairealtime.Config{
-		APIKey: os.Getenv("OPENAI_API_KEY"),
+	turnDetection := openairealtime.ServerVAD
+	if *eagerness != "" {
+		turnDetection = openairealtime.SemanticVAD
+	}
+	if *manualTurns {
+		turnDetection = openairealtime.ManualTurnDetection
+	}
+
+	// Set up the built-in tools that were turned on and the tools of the
+	// configured MCP servers, so the prompt can list them
+	var assistant provider.RealtimeProvider
+	tools, err := newBuiltinTools(*builtinTools, *toolsDir, *toolsCommands, *toolsHosts, func(text string) error {
+		return assistant.SendText(text)
 	})
 	if err != nil {
-		log.Fatalf("Failed to initialize OpenAI Realtime client: %v", err)
+		log.Fatalf("Failed to set up built-in tools: %v", err)
+	}
+	if *mcpConfig != "" {
+		config, err := mcp.Load(*mcpConfig)
+		if err != nil {
+			log.Fatalf("Failed to load MCP config: %v", err)
+		}
+		config.Logger = logger
+		servers, err := mcp.ConnectAll(context.Background(), config)
+		if err != nil {
+			log.Fatalf("Failed to connect to MCP servers: %v", err)
+		}
+		atExit(func() {
+			for _, server := range servers {
+				server.Close()
+			}
+		})
+		for _, server := range servers {
+			serverTools, err := server.Tools(context.Background())
+			if err != nil {
+				log.Fatalf("Failed to list MCP tools: %v", err)
+			}
+			tools = append(tools, serverTools...)
+			log.Infof("Found %d tools of MCP server %s", len(serverTools), server.Name())
+		}
+	}
+	toolNames := make([]string, len(tools))
+	for i, tool := range tools {
+		toolNames[i] = tool.Name
+	}
+
+	var prompt *instructions.Prompt
+	var initialInstructions string
+	if *promptFile != "" {
+		prompt = &instructions.Prompt{
+			Path: *promptFile,
+			Vars: instructions.Vars{
+				UserName: *userName,
+				Locale:   *locale,
+				Tools:    toolNames,
+			},
+			Logger: logger,
+		}
+		initialInstructions, err = prompt.Render()
+		if err != nil {
+			log.Fatalf("Failed to load prompt: %v", err)
+		}
+	}
+
+	// Tools that require confirmation wait for a keypress
+	approver := newKeyApprover()
+	approvals := approval.New(approval.Config{
+		Approver: approver.approve,
+		AuditLog: &auditFile{path: *auditLog},
+		Logger:   logger,
+		PII:      logPII,
+	})
+
+	var agents []openairealtime.Agent
+	if *agentsFile != "" {
+		agents, err = loadAgents(*agentsFile)
+		if err != nil {
+			log.Fatalf("Failed to load agents: %v", err)
+		}
+	}
+
+	var guardrails openairealtime.GuardrailOptions
+	if *guardrailWords != "" {
+		checker, err := guardrail.Keywords("blocked_words", splitList(*guardrailWords)...)
+		if err != nil {
+			log.Fatalf("Invalid guardrail words: %v", err)
+		}
+		guardrails = openairealtime.GuardrailOptions{Output: checker, Input: checker, Reply: *guardrailReply}
+	}
+
+	switch *backend {
+	case "openai":
+		// Get an OpenAI Realtime client
+		openaiRealtime, err := openairealtime.GetOpenAIRealtimeClient(openairealtime.Config{
+			APIKey:        os.Getenv("OPENAI_API_KEY"),
+			Protocol:      openairealtime.Protocol(*openaiProtocol),
+			Model:         *openaiModel,
+			Instructions:  initialInstructions,
+			DuplexMode:    openairealtime.DuplexMode(*duplex),
+			TurnDetection: turnDetection,
+			VAD: openairealtime.VADOptions{
+				Eagerness: openairealtime.Eagerness(*eagerness),
+			},
+			NoiseReduction: noiseReduction,
+			Transcription: openairealtime.TranscriptionOptions{
+				Model:    *transcriptionModel,
+				Language: *transcriptionLanguage,
+				Prompt:   *transcriptionPrompt,
+			},
+			InputAudioFormat:  audioFormat,
+			OutputAudioFormat: audioFormat,
+			Logger:            logger,
+			Approval:          approvals,
+			Agents:            agents,
+			Guardrails:        guardrails,
+			ModelPII:          modelPII,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize OpenAI Realtime client: %v", err)
+		}
+		openaiRealtime.AttachPlaybackMonitor(audioOutput)
+		assistant = openaiRealtime
+	case "gemini":
+		if audioFormat != audioformat.PCM16 {
+			log.Fatalf("The gemini backend only supports pcm16 audio")
+		}
+		geminiLive, err := geminilive.NewGeminiLiveClient(geminilive.Config{
+			Instructions: initialInstructions,
+			Logger:       logger,
+			Approval:     approvals,
+			ModelPII:     modelPII,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize Gemini Live client: %v", err)
+		}
+		if err := geminiLive.Connect(context.Background()); err != nil {
+			log.Fatalf("Failed to connect to Gemini Live: %v", err)
+		}
+		assistant = geminiLive
+	case "pipeline":
+		if audioFormat != audioformat.PCM16 {
+			log.Fatalf("The pipeline backend only supports pcm16 audio")
+		}
+		pipelineClient, err := pipeline.NewPipelineClient(pipeline.Config{
+			BaseURL:              *pipelineURL,
+			APIKey:               os.Getenv("PIPELINE_API_KEY"),
+			TranscriptionBaseURL: *sttURL,
+			ChatBaseURL:          *llmURL,
+			SpeechBaseURL:        *ttsURL,
+			ChatModel:            *llmModel,
+			Instructions:         initialInstructions,
+			Logger:               logger,
+			Approval:             approvals,
+			ModelPII:             modelPII,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize pipeline: %v", err)
+		}
+		assistant = pipelineClient
+	default:
+		log.Fatalf("Unknown backend: %s", *backend)
 	}
 
-	openaiRealtime.AttachAudioInput(inputchan)
-	openaiRealtime.AttachAudioOutput(outputchan)
+	// The rest of the app only depends on the provider interface
+
+	// Declare the built-in and MCP tools
+	confirm := make(map[string]bool)
+	for _, name := range splitList(*confirmTools) {
+		confirm[name] = true
+	}
+	async := make(map[string]bool)
+	for _, name := range splitList(*asyncTools) {
+		async[name] = true
+	}
+	registerTools(assistant, tools, confirm, async)
+
+	// Update the session whenever the prompt file changes
+	if updater, ok := assistant.(instructionsUpdater); ok && prompt != nil {
+		go prompt.Watch(time.Second, assistant.Done(), func(instructions string) {
+			if err := updater.SetInstructions(instructions); err != nil {
+				log.Errorf("Failed to update instructions: %v", err)
+			}
+		})
+	}
+
+	// Summarize the latency users felt when the app exits
+	if reporter, ok := assistant.(latencyReporter); ok {
+		atExit(func() { logLatencyReport(reporter.LatencyReport()) })
+	}
+
+	// With manual turns, the spacebar decides when each turn starts and ends
+	var turns PushToTalkHandler
+	if handler, ok := assistant.(PushToTalkHandler); ok && *manualTurns {
+		turns = handler
+	}
+	go UnmuteOnSpacebar(audioInput, turns, approver)
+
+	assistant.AttachAudioInput(inputchan)
+	assistant.AttachAudioOutput(outputchan)
+	assistant.OnEvent(eventLogger(logger))
+	// Stop playing responses as soon as they are interrupted, e.g. by the user talking over them
+	assistant.OnEvent(func(event provider.Event) {
+		if event.Type == provider.ResponseInterrupted {
+			audioOutput.DropResponse(event.ResponseID)
+		}
+	})
 
-	// Start the OpenAI Realtime client (blocking)
-	err = openaiRealtime.Start()
+	// Start the assistant (blocking)
+	err = assistant.Start()
 	if err != nil {
-		log.Fatalf("Failed to start OpenAI Realtime client: %v", err)
+		log.Fatalf("Failed to start assistant: %v", err)
 	}
+	exit(0)
 }
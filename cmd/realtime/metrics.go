@@ -0,0 +1,18 @@
+package main
+
+import (
+	"net/http"
+	"realtime/pkg/metrics"
+
+	"github.com/charmbracelet/log"
+)
+
+// serveMetrics serves the Prometheus metrics endpoint
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	log.Infof("Serving metrics on %s/metrics", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Errorf("Metrics server failed: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"realtime/pkg/openairealtime"
+	"strings"
+)
+
+// headsetKeywords identify headset microphones by their device name
+var headsetKeywords = []string{"headset", "headphone", "airpods", "buds", "earphone", "jabra", "plantronics", "poly"}
+
+// noiseReductionFor picks the noise reduction for the microphone in use. With
+// mic "auto" the default input device is a headset if its name says so, and
+// otherwise assumed to be a laptop microphone.
+func noiseReductionFor(mic string, deviceName string) (openairealtime.NoiseReductionMode, error) {
+	switch mic {
+	case "headset":
+		return openairealtime.NearField, nil
+	case "laptop":
+		return openairealtime.FarField, nil
+	case "off":
+		return openairealtime.NoNoiseReduction, nil
+	case "auto":
+		name := strings.ToLower(deviceName)
+		for _, keyword := range headsetKeywords {
+			if strings.Contains(name, keyword) {
+				return openairealtime.NearField, nil
+			}
+		}
+		return openairealtime.FarField, nil
+	default:
+		return "", fmt.Errorf("unknown microphone %q, use auto, headset, laptop or off", mic)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"realtime/pkg/pii"
+	"strings"
+)
+
+// piiPatterns collects the repeatable -pii-pattern flag as name=regexp pairs
+type piiPatterns map[string]string
+
+func (p piiPatterns) String() string {
+	pairs := make([]string, 0, len(p))
+	for name, pattern := range p {
+		pairs = append(pairs, name+"="+pattern)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p piiPatterns) Set(value string) error {
+	name, pattern, ok := strings.Cut(value, "=")
+	if !ok || name == "" || pattern == "" {
+		return fmt.Errorf("expected name=regexp, got %q", value)
+	}
+	p[name] = pattern
+	return nil
+}
+
+// newRedactor sets up the detectors named in the -pii-detectors flag, all
+// of them if it is empty, and the custom patterns
+func newRedactor(detectors string, patterns piiPatterns) (*pii.Redactor, error) {
+	return pii.New(pii.Config{
+		Detectors: splitList(detectors),
+		Custom:    patterns,
+	})
+}
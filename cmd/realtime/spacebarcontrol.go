@@ -1,7 +1,6 @@
 package main
 
 import (
-	"os"
 	"realtime/pkg/audioinput"
 	"time"
 
@@ -15,7 +14,7 @@ import (
 func checkExit(key keyboard.Key) {
 	if key == keyboard.KeyEsc {
 		keyboard.Close()
-		os.Exit(0)
+		exit(0)
 	}
 }
 
@@ -25,7 +24,16 @@ type KeyEvent struct {
 	key       keyboard.Key
 }
 
-func UnmuteOnSpacebar(audioInput *audioinput.StreamHandler) {
+// PushToTalkHandler is notified when the talk key is pressed and released
+type PushToTalkHandler interface {
+	StartTurn() error
+	EndTurn() error
+}
+
+// UnmuteOnSpacebar unmutes the audio input while the spacebar is held. If
+// turns is not nil it is told when each push-to-talk turn starts and ends.
+// While the approver shows a tool call, y and n answer it.
+func UnmuteOnSpacebar(audioInput *audioinput.StreamHandler, turns PushToTalkHandler, approver *keyApprover) {
 	// Setup keyboard events
 	if err := keyboard.Open(); err != nil {
 		log.Fatalf("Failed to initialize keyboard: %v", err)
@@ -44,6 +52,9 @@ func UnmuteOnSpacebar(audioInput *audioinput.StreamHandler) {
 				log.Printf("Error reading keyboard: %v", err)
 				continue
 			}
+			if approver.handleKey(char) {
+				continue
+			}
 			mu.Lock()
 			latestEvent = &KeyEvent{
 				timestamp: time.Now(),
@@ -64,6 +75,11 @@ func UnmuteOnSpacebar(audioInput *audioinput.StreamHandler) {
 			checkExit(event.key)
 			if event.key == keyboard.KeySpace {
 				log.Debug("Listening for audio input")
+				if turns != nil {
+					if err := turns.StartTurn(); err != nil {
+						log.Errorf("Failed to start turn: %v", err)
+					}
+				}
 				audioInput.Unmute()
 				lastEventTime := event.timestamp
 
@@ -96,6 +112,11 @@ func UnmuteOnSpacebar(audioInput *audioinput.StreamHandler) {
 					// No recent space event, exit loop
 					log.Debug("Audio input is muted")
 					audioInput.Mute()
+					if turns != nil {
+						if err := turns.EndTurn(); err != nil {
+							log.Errorf("Failed to end turn: %v", err)
+						}
+					}
 					break spaceLoop
 				}
 			}
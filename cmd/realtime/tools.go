@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"realtime/pkg/provider"
+	"realtime/pkg/tools"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// newBuiltinTools sets up the built-in tools named in the -tools flag and
+// those enabled by the allowlist flags. Finished timers are announced through
+// sendText, so the tools can be set up before the assistant.
+func newBuiltinTools(names, dir, commands, hosts string, sendText func(text string) error) ([]provider.Tool, error) {
+	config := tools.Config{
+		FilesDir:      dir,
+		ShellCommands: splitList(commands),
+		HTTPHosts:     splitList(hosts),
+		OnTimer: func(label string) {
+			log.Infof("Timer %q finished", label)
+			if err := sendText(fmt.Sprintf("[The timer %q you set has finished. Tell me.]", label)); err != nil {
+				log.Errorf("Failed to announce timer: %v", err)
+			}
+		},
+	}
+	for _, name := range splitList(names) {
+		switch name {
+		case "clock":
+			config.Clock = true
+		case "timers":
+			config.Timers = true
+		case "calculator":
+			config.Calculator = true
+		default:
+			return nil, fmt.Errorf("unknown built-in tool %q", name)
+		}
+	}
+	return tools.New(config)
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,154 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"realtime/pkg/logging"
+	"realtime/pkg/pii"
+	"realtime/pkg/provider"
+	"sync"
+	"time"
+)
+
+// defaultTimeout is how long a call waits for a decision before it is rejected
+const defaultTimeout = time.Minute
+
+// ErrRejected is wrapped by the errors of calls that were not approved
+var ErrRejected = errors.New("the user did not approve this action")
+
+// Decision is the answer to a tool call awaiting confirmation
+type Decision struct {
+	Approved bool
+	Reason   string // Optional, reported to the assistant on rejection
+	By       string // Who or what decided, e.g. keyboard, recorded in the audit log
+}
+
+// Approver shows a pending tool call to a human and blocks until they
+// decide or ctx is done. It is the callback for embedding apps, e.g. to ask
+// in a web UI.
+type Approver func(ctx context.Context, call provider.ToolCall) (Decision, error)
+
+// Config of a Gate
+type Config struct {
+	Approver Approver      // Asks for decisions, without it every call is rejected
+	AuditLog io.Writer     // Every decision is appended as a JSON line, optional
+	Timeout  time.Duration // Calls without a decision are rejected after this, defaults to 1m
+	Logger   *slog.Logger  // Defaults to a charm logger on stderr, secrets are always redacted
+	PII      *pii.Redactor // Redacts personal data in the arguments and reasons of the audit log, optional
+}
+
+// Gate holds calls of tools that require confirmation until a human decides
+// on them. A nil Gate rejects every call.
+type Gate struct {
+	approver Approver
+	timeout  time.Duration
+	logger   *slog.Logger
+	pii      *pii.Redactor
+
+	auditMu  sync.Mutex
+	auditLog io.Writer
+}
+
+// New returns a gate asking the approver of the config
+func New(config Config) *Gate {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	return &Gate{
+		approver: config.Approver,
+		timeout:  config.Timeout,
+		logger:   logging.OrDefault(config.Logger),
+		auditLog: config.AuditLog,
+		pii:      config.PII,
+	}
+}
+
+// auditEntry is a line of the audit log
+type auditEntry struct {
+	Time      time.Time       `json:"time"`
+	Tool      string          `json:"tool"`
+	CallID    string          `json:"call_id"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Decision  string          `json:"decision"` // approved, rejected, timeout or error
+	Reason    string          `json:"reason,omitempty"`
+	By        string          `json:"by,omitempty"`
+	Waited    string          `json:"waited"`
+}
+
+// Authorize asks for a decision on a call and returns nil if it was
+// approved. Otherwise the error, wrapping ErrRejected, is meant to be
+// reported to the assistant as the output of the call.
+func (g *Gate) Authorize(ctx context.Context, call provider.ToolCall) error {
+	if g == nil || g.approver == nil {
+		return fmt.Errorf("%w: %s requires confirmation and no one can approve it", ErrRejected, call.Name)
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	g.logger.Info("Tool call awaits confirmation", "tool", call.Name, "call_id", call.CallID)
+	decision, err := g.approver(ctx, call)
+
+	entry := auditEntry{
+		Time:      start,
+		Tool:      call.Name,
+		CallID:    call.CallID,
+		Arguments: g.sanitizeArguments(call.Arguments),
+		Reason:    decision.Reason,
+		By:        decision.By,
+		Waited:    time.Since(start).Round(time.Millisecond).String(),
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		entry.Decision = "timeout"
+		entry.Reason = fmt.Sprintf("no decision within %s", g.timeout)
+		err = fmt.Errorf("%w: %s", ErrRejected, entry.Reason)
+	case err != nil:
+		entry.Decision = "error"
+		entry.Reason = err.Error()
+		err = fmt.Errorf("%w: %v", ErrRejected, err)
+	case decision.Approved:
+		entry.Decision = "approved"
+	default:
+		entry.Decision = "rejected"
+		err = ErrRejected
+		if decision.Reason != "" {
+			err = fmt.Errorf("%w: %s", ErrRejected, decision.Reason)
+		}
+	}
+	g.audit(entry)
+	g.logger.Info("Tool call decided", "tool", call.Name, "call_id", call.CallID, "decision", entry.Decision, "by", entry.By)
+	return err
+}
+
+// audit appends an entry to the audit log
+func (g *Gate) audit(entry auditEntry) {
+	if g.auditLog == nil {
+		return
+	}
+	entry.Reason = g.pii.Redact(entry.Reason)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		g.logger.Error("Error encoding audit entry", "tool", entry.Tool, "call_id", entry.CallID, "error", err)
+		return
+	}
+	g.auditMu.Lock()
+	defer g.auditMu.Unlock()
+	if _, err := g.auditLog.Write(append(line, '\n')); err != nil {
+		g.logger.Error("Error writing audit log", "tool", entry.Tool, "call_id", entry.CallID, "error", err)
+	}
+}
+
+// sanitizeArguments redacts secrets and personal data in the arguments
+// recorded in the audit log
+func (g *Gate) sanitizeArguments(arguments json.RawMessage) json.RawMessage {
+	sanitized := json.RawMessage(g.pii.Redact(logging.Sanitize(string(arguments))))
+	if !json.Valid(sanitized) {
+		return nil
+	}
+	return sanitized
+}
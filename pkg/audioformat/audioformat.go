@@ -0,0 +1,118 @@
+package audioformat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Format is an audio encoding supported by the realtime session
+type Format string
+
+const (
+	// PCM16 is 16-bit little-endian PCM at 24kHz
+	PCM16 Format = "pcm16"
+	// G711ULaw is 8-bit G.711 mu-law at 8kHz, as used by North American telephony
+	G711ULaw Format = "g711_ulaw"
+	// G711ALaw is 8-bit G.711 a-law at 8kHz, as used by European telephony
+	G711ALaw Format = "g711_alaw"
+)
+
+// Validate returns an error if the format is not supported
+func (f Format) Validate() error {
+	switch f {
+	case PCM16, G711ULaw, G711ALaw:
+		return nil
+	default:
+		return fmt.Errorf("unknown audio format: %q", f)
+	}
+}
+
+// SampleRate returns the sample rate in Hz of the format
+func (f Format) SampleRate() int {
+	if f == PCM16 {
+		return 24000
+	}
+	return 8000
+}
+
+// BytesPerSample returns the size in bytes of one sample of the format
+func (f Format) BytesPerSample() int {
+	if f == PCM16 {
+		return 2
+	}
+	return 1
+}
+
+// Duration returns the playback duration of n bytes of audio in the format
+func (f Format) Duration(n int) time.Duration {
+	samples := n / f.BytesPerSample()
+	return time.Duration(samples) * time.Second / time.Duration(f.SampleRate())
+}
+
+// Encode converts samples to the given format
+func Encode(format Format, samples []int16) []byte {
+	switch format {
+	case G711ULaw:
+		out := make([]byte, len(samples))
+		for i, s := range samples {
+			out[i] = EncodeULaw(s)
+		}
+		return out
+	case G711ALaw:
+		out := make([]byte, len(samples))
+		for i, s := range samples {
+			out[i] = EncodeALaw(s)
+		}
+		return out
+	default:
+		out := make([]byte, len(samples)*2)
+		for i, s := range samples {
+			binary.LittleEndian.PutUint16(out[i*2:], uint16(s))
+		}
+		return out
+	}
+}
+
+// Decode converts audio in the given format to samples
+func Decode(format Format, data []byte) ([]int16, error) {
+	switch format {
+	case G711ULaw:
+		out := make([]int16, len(data))
+		for i, b := range data {
+			out[i] = DecodeULaw(b)
+		}
+		return out, nil
+	case G711ALaw:
+		out := make([]int16, len(data))
+		for i, b := range data {
+			out[i] = DecodeALaw(b)
+		}
+		return out, nil
+	default:
+		if len(data)%2 != 0 {
+			return nil, errors.New("byte array length must be even for PCM16 data")
+		}
+		out := make([]int16, len(data)/2)
+		for i := range out {
+			out[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+		}
+		return out, nil
+	}
+}
+
+// RMS returns the RMS level (0-1) of the given samples
+func RMS(samples []int16) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range samples {
+		sample := float64(s) / math.MaxInt16
+		sum += sample * sample
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
@@ -0,0 +1,85 @@
+package audioformat
+
+// G.711 companding as described in ITU-T Recommendation G.711
+
+const (
+	uLawBias = 0x84
+	uLawClip = 32635
+)
+
+// EncodeULaw compresses a 16-bit sample to 8-bit mu-law
+func EncodeULaw(sample int16) byte {
+	s := int(sample)
+	sign := 0
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > uLawClip {
+		s = uLawClip
+	}
+	s += uLawBias
+
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> (exponent + 3)) & 0x0f
+
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// DecodeULaw expands an 8-bit mu-law value to a 16-bit sample
+func DecodeULaw(b byte) int16 {
+	u := ^b
+	exponent := int(u>>4) & 0x07
+	mantissa := int(u & 0x0f)
+
+	s := ((mantissa << 3) + uLawBias) << exponent
+	s -= uLawBias
+	if u&0x80 != 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
+
+// EncodeALaw compresses a 16-bit sample to 8-bit a-law
+func EncodeALaw(sample int16) byte {
+	s := int(sample) >> 3 // a-law works on 13-bit samples
+	sign := 0x80
+	if s < 0 {
+		s = -s - 1
+		sign = 0
+	}
+
+	var value int
+	if s < 32 {
+		value = s >> 1
+	} else {
+		exponent := 1
+		for v := s >> 5; v > 1 && exponent < 7; v >>= 1 {
+			exponent++
+		}
+		value = exponent<<4 | (s>>exponent)&0x0f
+	}
+
+	return byte(sign|value) ^ 0x55
+}
+
+// DecodeALaw expands an 8-bit a-law value to a 16-bit sample
+func DecodeALaw(b byte) int16 {
+	a := b ^ 0x55
+	exponent := int(a>>4) & 0x07
+	mantissa := int(a & 0x0f)
+
+	var s int
+	if exponent == 0 {
+		s = mantissa<<4 + 8
+	} else {
+		s = (mantissa<<4 + 0x108) << (exponent - 1)
+	}
+	if a&0x80 == 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
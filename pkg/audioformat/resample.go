@@ -0,0 +1,46 @@
+package audioformat
+
+// Resample converts mono samples from one sample rate to another. When
+// downsampling, each output sample averages the input samples it covers to
+// avoid aliasing; when upsampling, samples are linearly interpolated.
+func Resample(samples []int16, from, to int) []int16 {
+	if from == to || from <= 0 || to <= 0 || len(samples) == 0 {
+		return samples
+	}
+
+	outLen := len(samples) * to / from
+	out := make([]int16, outLen)
+	ratio := float64(from) / float64(to)
+
+	if to < from {
+		for i := range out {
+			start := int(float64(i) * ratio)
+			end := int(float64(i+1) * ratio)
+			if end > len(samples) {
+				end = len(samples)
+			}
+			if end <= start {
+				end = start + 1
+			}
+
+			var sum int
+			for _, s := range samples[start:end] {
+				sum += int(s)
+			}
+			out[i] = int16(sum / (end - start))
+		}
+		return out
+	}
+
+	for i := range out {
+		pos := float64(i) * ratio
+		index := int(pos)
+		next := index + 1
+		if next >= len(samples) {
+			next = len(samples) - 1
+		}
+		frac := pos - float64(index)
+		out[i] = int16(float64(samples[index])*(1-frac) + float64(samples[next])*frac)
+	}
+	return out
+}
@@ -2,79 +2,101 @@ package audioinput
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
+	"realtime/pkg/audioformat"
+	"realtime/pkg/logging"
+	"realtime/pkg/metrics"
 	"time"
 
-	"github.com/charmbracelet/log"
-
 	"github.com/gordonklaus/portaudio"
 )
 
 // Config holds configuration for the audio capture
 type Config struct {
-	Channels        int // Number of audio channels (e.g., 1 for mono, 2 for stereo)
-	SampleRate      int // Sample rate in Hz (e.g., 44100 for CD quality)
-	FramesPerBuffer int // Number of frames per buffer
+	Channels        int                // Number of audio channels (e.g., 1 for mono, 2 for stereo)
+	SampleRate      int                // Sample rate in Hz (e.g., 44100 for CD quality)
+	FramesPerBuffer int                // Number of frames per buffer
+	Format          audioformat.Format // Format of the chunks sent on the channel, defaults to PCM16
+	Logger          *slog.Logger       // Defaults to a charm logger on stderr
 }
 
 // StreamHandler wraps the PortAudio stream
 type StreamHandler struct {
 	stream *portaudio.Stream
 	config Config
+	logger *slog.Logger
 	muted  bool // Add muted flag
 }
 
 // Init initializes the PortAudio library
 func Init(config Config) (*StreamHandler, error) {
-	if err := portaudio.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize PortAudio: %v", err)
+	if config.Format == "" {
+		config.Format = audioformat.PCM16
+	}
+	if err := config.Format.Validate(); err != nil {
 		return nil, err
 	}
 
+	logger := logging.OrDefault(config.Logger)
+
+	if err := portaudio.Initialize(); err != nil {
+		logger.Error("Failed to initialize PortAudio", "error", err)
+		return nil, fmt.Errorf("failed to initialize PortAudio: %w", err)
+	}
+
 	// List available input devices
 	devices, err := portaudio.Devices()
 	if err != nil {
-		log.Warnf("Failed to list available input devices: %v", err)
+		logger.Warn("Failed to list available input devices", "error", err)
 	} else {
-		log.Infof("Available Input Devices:")
+		logger.Info("Available input devices:")
 		for i, device := range devices {
 			if device.MaxInputChannels > 0 {
-				log.Infof("[%d] %s", i, device.Name)
+				logger.Info("Input device", "index", i, "name", device.Name)
 			}
 		}
 	}
 
-	sh := &StreamHandler{config: config}
+	sh := &StreamHandler{config: config, logger: logger}
 	return sh, nil
 }
 
-func monitorChannel(chunkChan <-chan []byte) {
-	log.Debug("Monitoring audio buffer usage")
+func (sh *StreamHandler) monitorChannel(chunkChan <-chan []byte) {
+	sh.logger.Debug("Monitoring audio buffer usage")
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		log.Infof("Monitoring audio buffer usage tick")
+		sh.logger.Debug("Monitoring audio buffer usage tick")
 		// Get current channel capacity usage
 		used := len(chunkChan)
 		capacity := cap(chunkChan)
 		usagePercent := float64(used) / float64(capacity) * 100
+		metrics.MicChannelFill.Set(float64(used) / float64(capacity))
 
 		// Log based on severity
 		switch {
 		case usagePercent > 80:
-			log.Warnf("Audio buffer nearly full! Usage: %.1f%% (%d/%d)",
-				usagePercent, used, capacity)
+			sh.logger.Warn("Audio buffer nearly full!", "usage_percent", usagePercent, "used", used, "capacity", capacity)
 		case usagePercent > 50:
-			log.Infof("Audio buffer usage: %.1f%% (%d/%d)",
-				usagePercent, used, capacity)
+			sh.logger.Info("Audio buffer usage", "usage_percent", usagePercent, "used", used, "capacity", capacity)
 		default:
-			log.Infof("Audio buffer usage: %.1f%% (%d/%d)",
-				usagePercent, used, capacity)
+			sh.logger.Debug("Audio buffer usage", "usage_percent", usagePercent, "used", used, "capacity", capacity)
 		}
 	}
 }
 
-// Listen starts capturing audio and returns a channel where PCM16 chunks are sent
+// DeviceName returns the name of the default input device, which Listen captures from
+func (sh *StreamHandler) DeviceName() (string, error) {
+	device, err := portaudio.DefaultInputDevice()
+	if err != nil {
+		return "", err
+	}
+	return device.Name, nil
+}
+
+// Listen starts capturing audio and returns a channel where chunks in the configured format are sent
 func (sh *StreamHandler) Listen() (<-chan []byte, error) {
 	if sh.config.Channels <= 0 {
 		return nil, errors.New("invalid number of channels")
@@ -85,19 +107,19 @@ func (sh *StreamHandler) Listen() (<-chan []byte, error) {
 	buffer := make([]int16, sh.config.FramesPerBuffer*sh.config.Channels)
 
 	// Add monitoring goroutine
-	go monitorChannel(chunkChan)
+	go sh.monitorChannel(chunkChan)
 
 	stream, err := portaudio.OpenDefaultStream(
 		sh.config.Channels, 0, float64(sh.config.SampleRate), len(buffer), func(input []int16) {
 			if !sh.muted {
-				chunkChan <- int16ToLittleEndian(input)
+				chunkChan <- sh.encode(input)
 			}
 		},
 	)
 	if err != nil {
-		log.Fatalf("Failed to open default stream: %v", err)
+		sh.logger.Error("Failed to open default stream", "error", err)
 		close(chunkChan)
-		return nil, err
+		return nil, fmt.Errorf("failed to open default stream: %w", err)
 	}
 
 	sh.stream = stream
@@ -105,8 +127,9 @@ func (sh *StreamHandler) Listen() (<-chan []byte, error) {
 	go func() {
 		defer close(chunkChan)
 		if err := stream.Start(); err != nil {
-			// Handle stream start error
-			log.Fatalf("Failed to start stream: %v", err)
+			// Closing the channel tells the listener that no audio will come
+			sh.logger.Error("Failed to start stream", "error", err)
+			return
 		}
 		// Stream will run until closed externally
 		select {}
@@ -115,6 +138,16 @@ func (sh *StreamHandler) Listen() (<-chan []byte, error) {
 	return chunkChan, nil
 }
 
+// encode converts captured samples to the configured format, resampling
+// to the format's sample rate for G.711
+func (sh *StreamHandler) encode(input []int16) []byte {
+	if sh.config.Format == audioformat.PCM16 {
+		return int16ToLittleEndian(input)
+	}
+	samples := audioformat.Resample(input, sh.config.SampleRate, sh.config.Format.SampleRate())
+	return audioformat.Encode(sh.config.Format, samples)
+}
+
 // Close stops the stream and terminates PortAudio
 func (sh *StreamHandler) Close() error {
 	if sh.stream != nil {
@@ -131,13 +164,13 @@ func (sh *StreamHandler) Close() error {
 // Mute stops sending audio data without closing the stream
 func (sh *StreamHandler) Mute() {
 	sh.muted = true
-	log.Debug("Audio input muted")
+	sh.logger.Debug("Audio input muted")
 }
 
 // Unmute resumes sending audio data
 func (sh *StreamHandler) Unmute() {
 	sh.muted = false
-	log.Debug("Audio input unmuted")
+	sh.logger.Debug("Audio input unmuted")
 }
 
 // IsMuted returns the current mute status
@@ -3,39 +3,107 @@ package audiooutput
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"realtime/pkg/audioformat"
+	"realtime/pkg/logging"
+	"realtime/pkg/metrics"
+	"realtime/pkg/provider"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
 )
 
+// underrunWindow is how soon after playback runs dry more audio must arrive
+// for it to count as an underrun rather than the end of a response
+const underrunWindow = time.Second
+
+// silenceThreshold is the sample amplitude below which audio counts as silent
+const silenceThreshold = 64
+
 type Config struct {
 	Channels        int
 	SampleRate      int
 	FramesPerBuffer int
+	Format          audioformat.Format // Format of the chunks played from the channel, defaults to PCM16
+	Logger          *slog.Logger       // Defaults to a charm logger on stderr
 }
 
 type StreamHandler struct {
 	config Config
+	logger *slog.Logger
 	stream *portaudio.Stream
+	level  atomic.Uint64 // float64 bits of the RMS level of the last played buffer
+	dryAt  atomic.Int64  // Unix nanoseconds when playback last ran out of audio mid-buffer
+
+	onPlaybackStart atomic.Value // func(time.Time), see OnPlaybackStart
+	buffer          atomic.Pointer[CircularBuffer]
+
+	mu        sync.Mutex    // Guards queue and dropped, and keeps them in step with the buffer
+	queue     []queuedAudio // Audio in the buffer by item, oldest first
+	dropped   map[string]bool
+	droppedAt []string // Dropped response IDs, oldest first, to bound dropped
+}
+
+// maxDropped is how many dropped responses are remembered to discard their late audio
+const maxDropped = 64
+
+// queuedAudio is the audio of an item in the playback buffer
+type queuedAudio struct {
+	responseID   string
+	itemID       string
+	contentIndex int
+	start, end   int64 // Buffer positions of its first sample and the one after its last
+}
+
+// Playback is the position of playback within the audio of an item
+type Playback struct {
+	ResponseID   string
+	ItemID       string
+	ContentIndex int
+	Played       time.Duration // Audio of the item played so far
+	Pending      time.Duration // Audio of the item buffered and not played yet
+}
+
+// OnPlaybackStart registers a function called with the time the first
+// non-silent sample is played after the buffer has run empty, i.e. when the
+// user starts hearing each response
+func (sh *StreamHandler) OnPlaybackStart(f func(at time.Time)) {
+	sh.onPlaybackStart.Store(f)
 }
 
 func Init(config Config) (*StreamHandler, error) {
+	if config.Format == "" {
+		config.Format = audioformat.PCM16
+	}
+	if err := config.Format.Validate(); err != nil {
+		return nil, err
+	}
+
 	if err := portaudio.Initialize(); err != nil {
 		return nil, err
 	}
 
 	return &StreamHandler{
 		config: config,
+		logger: logging.OrDefault(config.Logger),
 	}, nil
 }
 
-func (sh *StreamHandler) Play(audioChan <-chan []byte) error {
+// Play plays the chunks from audioChan in order until it is closed
+func (sh *StreamHandler) Play(audioChan <-chan provider.AudioChunk) error {
 	if sh.config.Channels <= 0 {
 		return errors.New("invalid number of channels")
 	}
 
 	// Create a circular buffer with enough capacity to handle bursts of data
 	circularBuffer := NewCircularBuffer(15000000)
+	sh.buffer.Store(circularBuffer)
+
+	// Only used by the stream callback
+	awaitingSound := true
 
 	// Open PortAudio stream
 	stream, err := portaudio.OpenDefaultStream(
@@ -44,7 +112,22 @@ func (sh *StreamHandler) Play(audioChan <-chan []byte) error {
 		sh.config.FramesPerBuffer, // Fixed size for PortAudio
 		func(out []int16) {
 			// Fill the output buffer from the circular buffer
-			circularBuffer.Read(out)
+			n := circularBuffer.Read(out)
+			if n > 0 && n < len(out) {
+				sh.dryAt.Store(time.Now().UnixNano())
+			}
+			if n == 0 {
+				awaitingSound = true
+			} else if awaitingSound {
+				if i := firstSound(out[:n]); i >= 0 {
+					awaitingSound = false
+					at := time.Now().Add(time.Duration(i/sh.config.Channels) * time.Second / time.Duration(sh.config.SampleRate))
+					if f, ok := sh.onPlaybackStart.Load().(func(time.Time)); ok {
+						go f(at)
+					}
+				}
+			}
+			sh.level.Store(math.Float64bits(audioformat.RMS(out)))
 		},
 	)
 	if err != nil {
@@ -60,22 +143,178 @@ func (sh *StreamHandler) Play(audioChan <-chan []byte) error {
 	// Start playback loop
 	go func() {
 		defer stream.Close()
-		for audioData := range audioChan {
+		for chunk := range audioChan {
 			// Convert byte slice to int16 slice
-			int16Data, err := bytesToInt16(audioData)
+			int16Data, err := sh.decode(chunk.Audio)
 			if err != nil {
-				log.Printf("Error converting byte data to int16: %v", err)
+				sh.logger.Error("Error converting byte data to int16", "response_id", chunk.ResponseID, "error", err)
 				continue
 			}
-
-			// Write data to the circular buffer
-			circularBuffer.Write(int16Data)
+			sh.enqueue(circularBuffer, chunk, int16Data)
 		}
 	}()
 
 	return nil
 }
 
+// enqueue writes the samples of a chunk to the buffer, unless its response was dropped
+func (sh *StreamHandler) enqueue(buffer *CircularBuffer, chunk provider.AudioChunk, samples []int16) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.dropped[chunk.ResponseID] {
+		return
+	}
+
+	// Audio arriving just after playback ran dry means it was not delivered in time
+	if dryAt := sh.dryAt.Swap(0); dryAt != 0 && time.Since(time.Unix(0, dryAt)) < underrunWindow {
+		metrics.PlaybackUnderruns.Inc()
+	}
+
+	read, start := buffer.Positions()
+	sh.prune(read)
+	buffer.Write(samples)
+	end := start + int64(len(samples))
+
+	if n := len(sh.queue); n > 0 {
+		last := &sh.queue[n-1]
+		if last.responseID == chunk.ResponseID && last.itemID == chunk.ItemID && last.contentIndex == chunk.ContentIndex && last.end == start {
+			last.end = end
+			return
+		}
+	}
+	sh.queue = append(sh.queue, queuedAudio{
+		responseID:   chunk.ResponseID,
+		itemID:       chunk.ItemID,
+		contentIndex: chunk.ContentIndex,
+		start:        start,
+		end:          end,
+	})
+}
+
+// prune forgets the audio that has been played completely
+func (sh *StreamHandler) prune(read int64) {
+	played := 0
+	for played < len(sh.queue) && sh.queue[played].end <= read {
+		played++
+	}
+	sh.queue = sh.queue[played:]
+}
+
+// Playing returns the item whose audio is being played and how much of it
+// has been played, e.g. to truncate the item to what the user heard. It
+// returns false when there is nothing left to play.
+func (sh *StreamHandler) Playing() (Playback, bool) {
+	buffer := sh.buffer.Load()
+	if buffer == nil {
+		return Playback{}, false
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	read, _ := buffer.Positions()
+	sh.prune(read)
+	if len(sh.queue) == 0 {
+		return Playback{}, false
+	}
+	current := sh.queue[0]
+	return Playback{
+		ResponseID:   current.responseID,
+		ItemID:       current.itemID,
+		ContentIndex: current.contentIndex,
+		Played:       sh.duration(int(max(read-current.start, 0))),
+		Pending:      sh.duration(int(current.end - max(read, current.start))),
+	}, true
+}
+
+// DropResponse stops playing a response, e.g. one that was interrupted: its
+// audio that has not been played yet is discarded, as is any that arrives
+// later. It returns how long the discarded audio would have played.
+func (sh *StreamHandler) DropResponse(responseID string) time.Duration {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.dropped == nil {
+		sh.dropped = make(map[string]bool)
+	}
+	if !sh.dropped[responseID] {
+		sh.dropped[responseID] = true
+		sh.droppedAt = append(sh.droppedAt, responseID)
+		if len(sh.droppedAt) > maxDropped {
+			delete(sh.dropped, sh.droppedAt[0])
+			sh.droppedAt = sh.droppedAt[1:]
+		}
+	}
+
+	buffer := sh.buffer.Load()
+	if buffer == nil {
+		return 0
+	}
+	// Audio after the dropped audio moves up to take its place
+	var removed int64
+	queue := sh.queue[:0]
+	for _, audio := range sh.queue {
+		audio.start -= removed
+		audio.end -= removed
+		if audio.responseID == responseID {
+			n := int64(buffer.Remove(audio.start, audio.end))
+			audio.end -= n
+			removed += n
+		}
+		if audio.end > audio.start {
+			queue = append(queue, audio)
+		}
+	}
+	sh.queue = queue
+	return sh.duration(int(removed))
+}
+
+// duration returns how long a number of samples plays
+func (sh *StreamHandler) duration(samples int) time.Duration {
+	frames := samples / sh.config.Channels
+	return time.Duration(frames) * time.Second / time.Duration(sh.config.SampleRate)
+}
+
+// firstSound returns the index of the first non-silent sample, or -1
+func firstSound(samples []int16) int {
+	for i, s := range samples {
+		if s > silenceThreshold || s < -silenceThreshold {
+			return i
+		}
+	}
+	return -1
+}
+
+// decode converts a chunk in the configured format to samples, resampling
+// G.711 audio to the output sample rate
+func (sh *StreamHandler) decode(audioData []byte) ([]int16, error) {
+	if sh.config.Format == audioformat.PCM16 {
+		return bytesToInt16(audioData)
+	}
+	samples, err := audioformat.Decode(sh.config.Format, audioData)
+	if err != nil {
+		return nil, err
+	}
+	return audioformat.Resample(samples, sh.config.Format.SampleRate(), sh.config.SampleRate), nil
+}
+
+// Flush discards the audio that has not been played yet, e.g. when a
+// response is cut off, and returns how long it would have played
+func (sh *StreamHandler) Flush() time.Duration {
+	buffer := sh.buffer.Load()
+	if buffer == nil {
+		return 0
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.queue = nil
+	return sh.duration(buffer.Clear())
+}
+
+// Level returns the RMS level (0-1) of the most recently played audio
+func (sh *StreamHandler) Level() float64 {
+	return math.Float64frombits(sh.level.Load())
+}
+
 func (sh *StreamHandler) Close() error {
 	if sh.stream != nil {
 		return sh.stream.Close()
@@ -20,12 +20,16 @@ func bytesToInt16(byteArray []byte) ([]int16, error) {
 	return int16Array, nil
 }
 
+// CircularBuffer queues samples for playback. Samples have positions in the
+// stream of samples written, which identify them across reads.
 type CircularBuffer struct {
-	buffer []int16
-	size   int
-	start  int
-	end    int
-	mu     sync.Mutex
+	buffer  []int16
+	size    int
+	start   int
+	end     int
+	read    int64 // Position of the sample at start
+	written int64 // Position of the sample at end
+	mu      sync.Mutex
 }
 
 // NewCircularBuffer creates a new circular buffer with the given size
@@ -44,13 +48,58 @@ func (cb *CircularBuffer) Write(data []int16) {
 	for _, sample := range data {
 		cb.buffer[cb.end] = sample
 		cb.end = (cb.end + 1) % cb.size
+		cb.written++
 		if cb.end == cb.start { // Overwrite if full
 			cb.start = (cb.start + 1) % cb.size
+			cb.read++
 		}
 	}
 }
 
-// Read reads data from the buffer into the given slice
+// Positions returns the position of the next sample to be read and of the
+// next sample to be written
+func (cb *CircularBuffer) Positions() (read, written int64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.read, cb.written
+}
+
+// Remove discards the buffered samples at positions from up to to, moving
+// the samples after them up, and returns the number of samples discarded
+func (cb *CircularBuffer) Remove(from, to int64) int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	from = max(from, cb.read)
+	to = min(to, cb.written)
+	if from >= to {
+		return 0
+	}
+	n := to - from
+	index := func(position int64) int {
+		return (cb.start + int(position-cb.read)) % cb.size
+	}
+	for position := to; position < cb.written; position++ {
+		cb.buffer[index(position-n)] = cb.buffer[index(position)]
+	}
+	cb.written -= n
+	cb.end = index(cb.written)
+	return int(n)
+}
+
+// Clear discards the buffered data and returns the number of samples discarded
+func (cb *CircularBuffer) Clear() int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	n := (cb.end - cb.start + cb.size) % cb.size
+	cb.start = cb.end
+	cb.written = cb.read
+	return n
+}
+
+// Read reads data from the buffer into the given slice, filling the rest with
+// silence, and returns the number of samples read
 func (cb *CircularBuffer) Read(out []int16) int {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
@@ -61,11 +110,11 @@ func (cb *CircularBuffer) Read(out []int16) int {
 		cb.start = (cb.start + 1) % cb.size
 		count++
 	}
+	cb.read += int64(count)
 
 	// Fill the remaining space with silence (zeros)
-	for count < len(out) {
-		out[count] = 0
-		count++
+	for i := count; i < len(out); i++ {
+		out[i] = 0
 	}
 
 	return count
@@ -0,0 +1,563 @@
+package geminilive
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"realtime/pkg/audioformat"
+	"realtime/pkg/logging"
+	"realtime/pkg/metrics"
+	"realtime/pkg/provider"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultURL   = "wss://generativelanguage.googleapis.com/ws/google.ai.generativelanguage.v1beta.GenerativeService.BidiGenerateContent"
+	defaultModel = "models/gemini-2.0-flash-live-001"
+
+	// Gemini takes 16kHz PCM16 input and produces 24kHz PCM16 output
+	inputSampleRate = 16000
+	inputMimeType   = "audio/pcm;rate=16000"
+
+	// metricsBackend is the backend label of the client's Prometheus metrics
+	metricsBackend = "gemini"
+
+	// cancelTaskToolName is the tool registered with the first async tool, so
+	// the assistant can cancel tasks the user no longer wants
+	cancelTaskToolName = "cancel_task"
+)
+
+var _ provider.RealtimeProvider = (*GeminiLiveClient)(nil)
+
+// GeminiLiveClient speaks the Gemini Live bidirectional streaming protocol.
+// Attached audio is 24kHz PCM16 in both directions, like the OpenAI client.
+type GeminiLiveClient struct {
+	config        Config
+	logger        *slog.Logger
+	conn          *websocket.Conn
+	writeMu       sync.Mutex // websocket connections support one concurrent writer
+	audioOutput   chan<- provider.AudioChunk
+	audioInput    <-chan []byte
+	tools         map[string]provider.Tool
+	toolsMu       sync.Mutex
+	toolCalls     map[string]context.CancelFunc // Running tool calls by ID
+	toolCallNames map[string]string             // Tool names of running tool calls by ID
+	toolCallsMu   sync.Mutex
+	eventHandlers []provider.EventHandler
+	handlersMu    sync.Mutex
+	setupComplete chan struct{}
+	started       atomic.Bool
+	done          chan struct{}
+	closeOnce     sync.Once
+	ctx           context.Context // Cancelled when the client is closed
+	cancel        context.CancelFunc
+
+	// Turn state, only used by the event loop
+	responseID      string
+	inputTranscript strings.Builder
+	transcript      strings.Builder
+}
+
+// NewGeminiLiveClient initializes a new Gemini Live client without connecting it
+func NewGeminiLiveClient(config Config) (*GeminiLiveClient, error) {
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("GEMINI_API_KEY")
+	}
+	if config.APIKey == "" && config.URL == "" {
+		return nil, errors.New("GEMINI_API_KEY is not set")
+	}
+	if config.URL == "" {
+		config.URL = defaultURL
+	}
+	if config.Model == "" {
+		config.Model = defaultModel
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &GeminiLiveClient{
+		config:        config,
+		logger:        logging.OrDefault(config.Logger),
+		tools:         make(map[string]provider.Tool),
+		toolCalls:     make(map[string]context.CancelFunc),
+		toolCallNames: make(map[string]string),
+		setupComplete: make(chan struct{}),
+		done:          make(chan struct{}),
+		ctx:           ctx,
+		cancel:        cancel,
+	}, nil
+}
+
+// AttachAudioOutput attaches an audio output channel for assistant -> client communication
+func (c *GeminiLiveClient) AttachAudioOutput(output chan<- provider.AudioChunk) {
+	c.audioOutput = output
+}
+
+// AttachAudioInput attaches an audio input channel for client -> assistant communication
+func (c *GeminiLiveClient) AttachAudioInput(input <-chan []byte) {
+	c.audioInput = input
+}
+
+// OnEvent registers a handler for events from the assistant
+func (c *GeminiLiveClient) OnEvent(handler provider.EventHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.eventHandlers = append(c.eventHandlers, handler)
+}
+
+// RegisterTool declares a tool the assistant can call. Gemini sessions
+// cannot change their tools, so tools must be registered before Start.
+func (c *GeminiLiveClient) RegisterTool(tool provider.Tool) error {
+	if c.started.Load() {
+		return errors.New("tools must be registered before the session is started")
+	}
+	if tool.Name == "" {
+		return errors.New("tool name is required")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	if _, ok := c.tools[tool.Name]; ok {
+		return fmt.Errorf("tool %s is already registered", tool.Name)
+	}
+	c.tools[tool.Name] = tool
+
+	// The assistant cancels async tools it started when the user changes their mind
+	if _, ok := c.tools[cancelTaskToolName]; tool.Async && !ok {
+		c.tools[cancelTaskToolName] = c.cancelTaskTool()
+	}
+	return nil
+}
+
+// Connect opens the WebSocket connection to the Gemini Live API
+func (c *GeminiLiveClient) Connect(ctx context.Context) error {
+	if c.conn != nil {
+		return errors.New("client is already connected")
+	}
+
+	u, err := url.Parse(c.config.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if c.config.APIKey != "" {
+		query := u.Query()
+		query.Set("key", c.config.APIKey)
+		u.RawQuery = query.Encode()
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	metrics.Connected(metricsBackend, "", err)
+	if err != nil {
+		if resp != nil {
+			c.logger.Error("WebSocket dial error", "error", err, "status", resp.StatusCode)
+		}
+		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	c.conn = conn
+	return nil
+}
+
+// Start sends the setup message and streams audio until the client is closed
+func (c *GeminiLiveClient) Start() error {
+	if c.conn == nil {
+		return errors.New("client is not connected")
+	}
+	if c.audioOutput == nil {
+		return errors.New("audio output channel is not attached")
+	}
+	if c.audioInput == nil {
+		return errors.New("audio input channel is not attached")
+	}
+
+	c.started.Store(true)
+	if err := c.send(ClientMessage{Setup: c.newSetup()}); err != nil {
+		return fmt.Errorf("failed to send setup: %w", err)
+	}
+
+	go c.listenForEvents()
+
+	// Audio can only be sent once the server has accepted the setup
+	select {
+	case <-c.setupComplete:
+		c.logger.Info("Connected to server, setup complete")
+	case <-c.done:
+		return errors.New("connection closed before setup completed")
+	}
+
+	go c.listenForAudioInput()
+
+	<-c.done
+	return nil
+}
+
+// Done returns a channel that is closed when the client is closed
+func (c *GeminiLiveClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection to the assistant and stops the client
+func (c *GeminiLiveClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cancel()
+		if c.conn != nil {
+			err = c.conn.Close()
+			metrics.Disconnected(metricsBackend, "")
+		}
+	})
+	return err
+}
+
+// SendText sends a text message from the user and asks the assistant to respond
+func (c *GeminiLiveClient) SendText(text string) error {
+	return c.send(ClientMessage{ClientContent: &ClientContent{
+		Turns:        []Content{{Role: "user", Parts: []Part{{Text: c.config.ModelPII.Redact(text)}}}},
+		TurnComplete: true,
+	}})
+}
+
+func (c *GeminiLiveClient) newSetup() *Setup {
+	empty := json.RawMessage("{}")
+	setup := &Setup{
+		Model: c.config.Model,
+		GenerationConfig: GenerationConfig{
+			ResponseModalities: []string{"AUDIO"},
+		},
+		InputAudioTranscription:  &empty,
+		OutputAudioTranscription: &empty,
+	}
+
+	if c.config.Voice != "" {
+		setup.GenerationConfig.SpeechConfig = &SpeechConfig{}
+		setup.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName = c.config.Voice
+	}
+	if c.config.Instructions != "" {
+		setup.SystemInstruction = &Content{Parts: []Part{{Text: c.config.Instructions}}}
+	}
+
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	if len(c.tools) > 0 {
+		declarations := make([]FunctionDeclaration, 0, len(c.tools))
+		for _, tool := range c.tools {
+			declaration := FunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			}
+			if tool.Async {
+				declaration.Behavior = "NON_BLOCKING"
+			}
+			declarations = append(declarations, declaration)
+		}
+		setup.Tools = []Tool{{FunctionDeclarations: declarations}}
+	}
+
+	return setup
+}
+
+func (c *GeminiLiveClient) listenForEvents() {
+	defer c.Close()
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			c.logger.Error("Error reading message", "error", err)
+			return
+		}
+
+		var msg ServerMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			c.logger.Error("Error unmarshalling message", "error", err, "message", string(message))
+			continue
+		}
+
+		switch {
+		case msg.SetupComplete != nil:
+			select {
+			case <-c.setupComplete:
+				c.logger.Warn("Received setupComplete again")
+			default:
+				close(c.setupComplete)
+			}
+		case msg.ServerContent != nil:
+			c.handleServerContent(msg.ServerContent)
+		case msg.ToolCall != nil:
+			c.handleToolCall(msg.ToolCall)
+		case msg.ToolCallCancellation != nil:
+			c.handleToolCallCancellation(msg.ToolCallCancellation)
+		case msg.GoAway != nil:
+			c.logger.Warn("Server will disconnect", "time_left", msg.GoAway.TimeLeft)
+		default:
+			c.logger.Debug("Unhandled message", "message", string(message))
+		}
+	}
+}
+
+func (c *GeminiLiveClient) handleServerContent(content *ServerContent) {
+	if content.InputTranscription != nil {
+		c.inputTranscript.WriteString(content.InputTranscription.Text)
+		c.emit(provider.Event{Type: provider.InputTranscriptDelta, Text: content.InputTranscription.Text})
+	}
+
+	if content.ModelTurn != nil || content.OutputTranscription != nil {
+		c.startResponse()
+	}
+
+	if content.ModelTurn != nil {
+		for _, part := range content.ModelTurn.Parts {
+			if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/pcm") {
+				audio, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+				if err != nil {
+					c.logger.Error("Error decoding base64 audio", "response_id", c.responseID, "error", err)
+					continue
+				}
+				metrics.AudioBytes.WithLabelValues(metricsBackend, "out").Add(float64(len(audio)))
+				select {
+				case c.audioOutput <- provider.AudioChunk{ResponseID: c.responseID, Audio: audio}:
+				case <-c.done:
+					return
+				}
+			}
+		}
+	}
+
+	if content.OutputTranscription != nil && content.OutputTranscription.Text != "" {
+		c.transcript.WriteString(content.OutputTranscription.Text)
+		c.emit(provider.Event{
+			Type:       provider.TranscriptDelta,
+			ResponseID: c.responseID,
+			Text:       content.OutputTranscription.Text,
+		})
+	}
+
+	if content.Interrupted {
+		c.endResponse(provider.ResponseInterrupted)
+	}
+	if content.TurnComplete {
+		c.endResponse(provider.ResponseDone)
+	}
+}
+
+// startResponse begins tracking a model turn, Gemini turns have no IDs so one is generated
+func (c *GeminiLiveClient) startResponse() {
+	if c.responseID != "" {
+		return
+	}
+
+	if c.inputTranscript.Len() > 0 {
+		c.emit(provider.Event{Type: provider.InputTranscript, Text: c.inputTranscript.String()})
+		c.inputTranscript.Reset()
+	}
+
+	c.responseID = uuid.NewString()
+	c.emit(provider.Event{Type: provider.ResponseStarted, ResponseID: c.responseID})
+}
+
+func (c *GeminiLiveClient) endResponse(eventType provider.EventType) {
+	if c.responseID == "" {
+		return
+	}
+
+	if c.transcript.Len() > 0 {
+		c.emit(provider.Event{Type: provider.Transcript, ResponseID: c.responseID, Text: c.transcript.String()})
+		c.transcript.Reset()
+	}
+	c.emit(provider.Event{Type: eventType, ResponseID: c.responseID})
+	c.responseID = ""
+}
+
+func (c *GeminiLiveClient) handleToolCall(toolCall *ToolCall) {
+	for _, call := range toolCall.FunctionCalls {
+		c.emit(provider.Event{
+			Type:       provider.ToolCalled,
+			ResponseID: c.responseID,
+			ToolCall:   &provider.ToolCall{CallID: call.ID, Name: call.Name, Arguments: call.Args},
+		})
+
+		ctx, cancel := context.WithCancel(c.ctx)
+		c.toolCallsMu.Lock()
+		c.toolCalls[call.ID] = cancel
+		c.toolCallNames[call.ID] = call.Name
+		c.toolCallsMu.Unlock()
+
+		go c.runToolCall(ctx, call)
+	}
+}
+
+// runToolCall runs a tool handler and reports its output, unless the call was cancelled
+func (c *GeminiLiveClient) runToolCall(ctx context.Context, call FunctionCall) {
+	defer func() {
+		c.toolCallsMu.Lock()
+		if cancel, ok := c.toolCalls[call.ID]; ok {
+			cancel()
+			delete(c.toolCalls, call.ID)
+		}
+		delete(c.toolCallNames, call.ID)
+		c.toolCallsMu.Unlock()
+	}()
+
+	response := map[string]any{}
+	c.toolsMu.Lock()
+	tool, ok := c.tools[call.Name]
+	c.toolsMu.Unlock()
+	if !ok {
+		c.logger.Warn("Assistant called unknown tool", "tool", call.Name, "call_id", call.ID)
+		response["error"] = fmt.Sprintf("unknown tool %s", call.Name)
+	} else if err := c.authorize(ctx, tool, call); err != nil {
+		response["error"] = err.Error()
+	} else {
+		c.logger.Info("Calling tool", "tool", call.Name, "call_id", call.ID, "arguments", string(call.Args))
+		start := time.Now()
+		output, err := tool.Handler(ctx, call.Args)
+		metrics.ToolCalled(metricsBackend, call.Name, start, err)
+		if err != nil {
+			c.logger.Error("Tool failed", "tool", call.Name, "call_id", call.ID, "error", err)
+			response["error"] = c.config.ModelPII.Redact(err.Error())
+		} else {
+			response["output"] = c.config.ModelPII.Redact(output)
+		}
+	}
+
+	if ctx.Err() != nil {
+		c.logger.Info("Tool call was cancelled", "tool", call.Name, "call_id", call.ID)
+		return
+	}
+
+	functionResponse := FunctionResponse{ID: call.ID, Name: call.Name, Response: response}
+	if tool.Async {
+		// Announce the result once the assistant has finished talking
+		functionResponse.Scheduling = "WHEN_IDLE"
+	}
+	if err := c.send(ClientMessage{ToolResponse: &ToolResponse{
+		FunctionResponses: []FunctionResponse{functionResponse},
+	}}); err != nil {
+		c.logger.Error("Error sending tool output", "tool", call.Name, "call_id", call.ID, "error", err)
+	}
+}
+
+// authorize waits for the approval of calls of tools that require confirmation
+func (c *GeminiLiveClient) authorize(ctx context.Context, tool provider.Tool, call FunctionCall) error {
+	if !tool.RequiresConfirmation {
+		return nil
+	}
+	return c.config.Approval.Authorize(ctx, provider.ToolCall{CallID: call.ID, Name: call.Name, Arguments: call.Args})
+}
+
+func (c *GeminiLiveClient) handleToolCallCancellation(cancellation *ToolCallCancellation) {
+	c.toolCallsMu.Lock()
+	defer c.toolCallsMu.Unlock()
+	for _, id := range cancellation.IDs {
+		if cancel, ok := c.toolCalls[id]; ok {
+			cancel()
+			delete(c.toolCalls, id)
+		}
+		delete(c.toolCallNames, id)
+	}
+}
+
+// cancelTaskTool lets the assistant cancel the running calls of an async
+// tool when the user says never mind. Their results are dropped.
+func (c *GeminiLiveClient) cancelTaskTool() provider.Tool {
+	return provider.Tool{
+		Name:        cancelTaskToolName,
+		Description: "Cancel a background task that is still running, e.g. when the user says never mind",
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"tool":{"type":"string","description":"Name of the function whose running calls are cancelled"}},"required":["tool"]}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				Tool string `json:"tool"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			c.toolCallsMu.Lock()
+			defer c.toolCallsMu.Unlock()
+			cancelled := 0
+			for id, name := range c.toolCallNames {
+				if name == args.Tool {
+					c.toolCalls[id]()
+					delete(c.toolCalls, id)
+					delete(c.toolCallNames, id)
+					cancelled++
+				}
+			}
+			if cancelled == 0 {
+				return "", fmt.Errorf("no running task of %s", args.Tool)
+			}
+			return fmt.Sprintf("Cancelled %d task(s)", cancelled), nil
+		},
+	}
+}
+
+func (c *GeminiLiveClient) listenForAudioInput() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case audio, ok := <-c.audioInput:
+			if !ok {
+				return
+			}
+			samples, err := audioformat.Decode(audioformat.PCM16, audio)
+			if err != nil {
+				c.logger.Error("Error decoding audio input", "error", err)
+				continue
+			}
+			samples = audioformat.Resample(samples, audioformat.PCM16.SampleRate(), inputSampleRate)
+			input := audioformat.Encode(audioformat.PCM16, samples)
+
+			if err := c.send(ClientMessage{RealtimeInput: &RealtimeInput{Audio: &InlineData{
+				MimeType: inputMimeType,
+				Data:     base64.StdEncoding.EncodeToString(input),
+			}}}); err != nil {
+				c.logger.Error("Error sending audio input", "error", err)
+				continue
+			}
+			metrics.AudioBytes.WithLabelValues(metricsBackend, "in").Add(float64(len(input)))
+		}
+	}
+}
+
+// emit sends an event to all registered handlers
+func (c *GeminiLiveClient) emit(event provider.Event) {
+	c.handlersMu.Lock()
+	handlers := c.eventHandlers
+	c.handlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (c *GeminiLiveClient) send(msg ClientMessage) error {
+	if c.conn == nil {
+		return errors.New("client is not connected")
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshalling message: %w", err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, payload)
+}
@@ -0,0 +1,189 @@
+package geminilive
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"realtime/pkg/provider"
+
+	"github.com/gorilla/websocket"
+)
+
+// mockServer runs script against the first client that connects
+func mockServer(t *testing.T, script func(conn *websocket.Conn)) (string, <-chan struct{}) {
+	t.Helper()
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		script(conn)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), done
+}
+
+// readMessage reads a client message, the zero message on error. Scripts run
+// on the server's goroutine, so they report errors instead of stopping the test.
+func readMessage(t *testing.T, conn *websocket.Conn) ClientMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg ClientMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Errorf("reading client message: %v", err)
+	}
+	return msg
+}
+
+func writeMessage(t *testing.T, conn *websocket.Conn, msg string) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Errorf("writing server message: %v", err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	speech := base64.StdEncoding.EncodeToString([]byte{1, 0, 2, 0, 3, 0, 4, 0})
+
+	url, serverDone := mockServer(t, func(conn *websocket.Conn) {
+		setup := readMessage(t, conn).Setup
+		if setup == nil {
+			t.Error("first message is not a setup")
+			return
+		}
+		if setup.Model != "models/test" {
+			t.Errorf("setup model = %q, want models/test", setup.Model)
+		}
+		if len(setup.Tools) != 1 || len(setup.Tools[0].FunctionDeclarations) != 1 || setup.Tools[0].FunctionDeclarations[0].Name != "echo" {
+			t.Errorf("setup tools = %+v, want echo", setup.Tools)
+		}
+		// A repeated setupComplete must not close the client
+		writeMessage(t, conn, `{"setupComplete":{}}`)
+		writeMessage(t, conn, `{"setupComplete":{}}`)
+
+		input := readMessage(t, conn).RealtimeInput
+		if input == nil || input.Audio == nil {
+			t.Error("expected realtime audio input")
+			return
+		}
+		if input.Audio.MimeType != inputMimeType {
+			t.Errorf("input mime type = %q, want %q", input.Audio.MimeType, inputMimeType)
+		}
+		audio, _ := base64.StdEncoding.DecodeString(input.Audio.Data)
+		if len(audio) != 320 {
+			t.Errorf("input audio is %d bytes, want 320 after resampling 240 samples to 16kHz", len(audio))
+		}
+
+		writeMessage(t, conn, `{"serverContent":{"inputTranscription":{"text":"Echo hi"}}}`)
+		writeMessage(t, conn, `{"serverContent":{"modelTurn":{"parts":[{"inlineData":{"mimeType":"audio/pcm;rate=24000","data":"`+speech+`"}}]},"outputTranscription":{"text":"Sure"}}}`)
+		writeMessage(t, conn, `{"toolCall":{"functionCalls":[{"id":"call-1","name":"echo","args":{"text":"hi"}}]}}`)
+
+		response := readMessage(t, conn).ToolResponse
+		if response == nil || len(response.FunctionResponses) != 1 {
+			t.Errorf("expected one tool response, got %+v", response)
+			return
+		}
+		if got := response.FunctionResponses[0]; got.ID != "call-1" || got.Name != "echo" || got.Response["output"] != "hi" {
+			t.Errorf("tool response = %+v, want output hi for call-1", got)
+		}
+
+		writeMessage(t, conn, `{"serverContent":{"interrupted":true}}`)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		conn.ReadMessage() // Wait for the client to close
+	})
+
+	client, err := NewGeminiLiveClient(Config{URL: url, Model: "models/test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RegisterTool(provider.Tool{
+		Name:       "echo",
+		Parameters: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				Text string `json:"text"`
+			}
+			err := json.Unmarshal(arguments, &args)
+			return args.Text, err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan provider.Event, 32)
+	client.OnEvent(func(event provider.Event) { events <- event })
+	output := make(chan provider.AudioChunk, 8)
+	input := make(chan []byte, 1)
+	input <- make([]byte, 480) // 10ms at 24kHz
+	client.AttachAudioOutput(output)
+	client.AttachAudioInput(input)
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan error, 1)
+	go func() { started <- client.Start() }()
+
+	var responseID string
+	want := []provider.EventType{
+		provider.InputTranscriptDelta,
+		provider.InputTranscript,
+		provider.ResponseStarted,
+		provider.TranscriptDelta,
+		provider.ToolCalled,
+		provider.Transcript,
+		provider.ResponseInterrupted,
+	}
+	for _, eventType := range want {
+		select {
+		case event := <-events:
+			if event.Type != eventType {
+				t.Fatalf("event = %s %q, want %s", event.Type, event.Text, eventType)
+			}
+			switch event.Type {
+			case provider.InputTranscript:
+				if event.Text != "Echo hi" {
+					t.Errorf("input transcript = %q, want Echo hi", event.Text)
+				}
+			case provider.ResponseStarted:
+				responseID = event.ResponseID
+			case provider.Transcript:
+				if event.Text != "Sure" || event.ResponseID != responseID {
+					t.Errorf("transcript = %q of %s, want Sure of %s", event.Text, event.ResponseID, responseID)
+				}
+			case provider.ToolCalled:
+				if event.ToolCall.Name != "echo" || event.ToolCall.CallID != "call-1" {
+					t.Errorf("tool call = %+v, want echo call-1", event.ToolCall)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", eventType)
+		}
+	}
+
+	select {
+	case chunk := <-output:
+		if chunk.ResponseID != responseID || len(chunk.Audio) != 8 {
+			t.Errorf("audio chunk of %s with %d bytes, want %s with 8 bytes", chunk.ResponseID, len(chunk.Audio), responseID)
+		}
+	default:
+		t.Error("no audio was output")
+	}
+
+	client.Close()
+	if err := <-started; err != nil {
+		t.Errorf("Start: %v", err)
+	}
+	<-serverDone
+}
@@ -0,0 +1,147 @@
+package geminilive
+
+import (
+	"encoding/json"
+	"log/slog"
+	"realtime/pkg/approval"
+	"realtime/pkg/pii"
+)
+
+// ClientMessage is a message sent to the server, exactly one field is set
+type ClientMessage struct {
+	Setup         *Setup         `json:"setup,omitempty"`
+	ClientContent *ClientContent `json:"clientContent,omitempty"`
+	RealtimeInput *RealtimeInput `json:"realtimeInput,omitempty"`
+	ToolResponse  *ToolResponse  `json:"toolResponse,omitempty"`
+}
+
+// Setup configures the session, it is the first message sent
+type Setup struct {
+	Model                    string           `json:"model"`
+	GenerationConfig         GenerationConfig `json:"generationConfig"`
+	SystemInstruction        *Content         `json:"systemInstruction,omitempty"`
+	Tools                    []Tool           `json:"tools,omitempty"`
+	InputAudioTranscription  *json.RawMessage `json:"inputAudioTranscription,omitempty"`
+	OutputAudioTranscription *json.RawMessage `json:"outputAudioTranscription,omitempty"`
+}
+
+type GenerationConfig struct {
+	ResponseModalities []string      `json:"responseModalities"`
+	SpeechConfig       *SpeechConfig `json:"speechConfig,omitempty"`
+}
+
+type SpeechConfig struct {
+	VoiceConfig struct {
+		PrebuiltVoiceConfig struct {
+			VoiceName string `json:"voiceName"`
+		} `json:"prebuiltVoiceConfig"`
+	} `json:"voiceConfig"`
+}
+
+// Tool declares the functions the model can call
+type Tool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations"`
+}
+
+type FunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // OpenAPI schema of the arguments
+	Behavior    string          `json:"behavior,omitempty"`   // NON_BLOCKING lets the conversation go on while the function runs
+}
+
+// Content is a turn of the conversation
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+// Part is text or inline media in a turn
+type Part struct {
+	Text       string      `json:"text,omitempty"`
+	InlineData *InlineData `json:"inlineData,omitempty"`
+}
+
+type InlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64 encoded
+}
+
+// ClientContent adds turns to the conversation
+type ClientContent struct {
+	Turns        []Content `json:"turns"`
+	TurnComplete bool      `json:"turnComplete"`
+}
+
+// RealtimeInput streams microphone audio
+type RealtimeInput struct {
+	Audio *InlineData `json:"audio,omitempty"`
+}
+
+// ToolResponse reports the outputs of tool calls
+type ToolResponse struct {
+	FunctionResponses []FunctionResponse `json:"functionResponses"`
+}
+
+type FunctionResponse struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+	// How the response of a NON_BLOCKING function is announced: SILENT, WHEN_IDLE or INTERRUPT
+	Scheduling string `json:"scheduling,omitempty"`
+}
+
+// ServerMessage is a message received from the server, exactly one field is set
+type ServerMessage struct {
+	SetupComplete        *json.RawMessage      `json:"setupComplete,omitempty"`
+	ServerContent        *ServerContent        `json:"serverContent,omitempty"`
+	ToolCall             *ToolCall             `json:"toolCall,omitempty"`
+	ToolCallCancellation *ToolCallCancellation `json:"toolCallCancellation,omitempty"`
+	GoAway               *GoAway               `json:"goAway,omitempty"`
+}
+
+// ServerContent is generated by the model in response to the client
+type ServerContent struct {
+	ModelTurn           *Content       `json:"modelTurn,omitempty"`
+	TurnComplete        bool           `json:"turnComplete"`
+	Interrupted         bool           `json:"interrupted"`
+	InputTranscription  *Transcription `json:"inputTranscription,omitempty"`
+	OutputTranscription *Transcription `json:"outputTranscription,omitempty"`
+}
+
+type Transcription struct {
+	Text string `json:"text"`
+}
+
+// ToolCall asks the client to run functions
+type ToolCall struct {
+	FunctionCalls []FunctionCall `json:"functionCalls"`
+}
+
+type FunctionCall struct {
+	ID   string          `json:"id"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+}
+
+// ToolCallCancellation tells the client that tool calls should be cancelled,
+// e.g. because the user interrupted the model
+type ToolCallCancellation struct {
+	IDs []string `json:"ids"`
+}
+
+// GoAway warns that the server will disconnect soon
+type GoAway struct {
+	TimeLeft string `json:"timeLeft"`
+}
+
+type Config struct {
+	APIKey       string // Defaults to GEMINI_API_KEY
+	URL          string // Defaults to the Gemini Live endpoint, e.g. a local mock server for testing
+	Model        string // Defaults to models/gemini-2.0-flash-live-001
+	Instructions string
+	Voice        string         // Prebuilt voice name, e.g. Puck
+	Logger       *slog.Logger   // Defaults to a charm logger on stderr, secrets are always redacted
+	Approval     *approval.Gate // Decides on calls of tools that require confirmation, which are rejected without it
+	ModelPII     *pii.Redactor  // Redacts personal data in text and tool outputs sent to the model, optional
+}
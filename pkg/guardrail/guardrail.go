@@ -0,0 +1,78 @@
+package guardrail
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Violation is a breach of a policy found in a transcript
+type Violation struct {
+	Rule   string // Name of the rule that was broken
+	Reason string // What was found, for logs and the audit trail
+}
+
+func (v *Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Reason)
+}
+
+// Checker checks a transcript against a policy. Transcripts are checked in
+// full as they grow, so rules can match across streamed deltas. Check
+// returns nil if the text is allowed.
+type Checker interface {
+	Check(ctx context.Context, text string) (*Violation, error)
+}
+
+// CheckerFunc adapts a function, e.g. a call to a moderation classifier, to a Checker
+type CheckerFunc func(ctx context.Context, text string) (*Violation, error)
+
+// Check calls f
+func (f CheckerFunc) Check(ctx context.Context, text string) (*Violation, error) {
+	return f(ctx, text)
+}
+
+// Keywords returns a checker that finds any of the words or phrases,
+// ignoring case. Words only match whole, e.g. "ass" does not match "class".
+func Keywords(rule string, words ...string) (Checker, error) {
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			patterns = append(patterns, `(?i)\b`+regexp.QuoteMeta(word)+`\b`)
+		}
+	}
+	return Regexp(rule, patterns...)
+}
+
+// Regexp returns a checker that finds any of the regular expressions
+func Regexp(rule string, patterns ...string) (Checker, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of rule %s: %w", rule, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return CheckerFunc(func(ctx context.Context, text string) (*Violation, error) {
+		for _, re := range compiled {
+			if match := re.FindString(text); match != "" {
+				return &Violation{Rule: rule, Reason: fmt.Sprintf("matched %q", match)}, nil
+			}
+		}
+		return nil, nil
+	}), nil
+}
+
+// All returns a checker that runs checkers in order and returns the first violation
+func All(checkers ...Checker) Checker {
+	return CheckerFunc(func(ctx context.Context, text string) (*Violation, error) {
+		for _, checker := range checkers {
+			violation, err := checker.Check(ctx, text)
+			if err != nil || violation != nil {
+				return violation, err
+			}
+		}
+		return nil, nil
+	})
+}
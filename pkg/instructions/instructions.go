@@ -0,0 +1,98 @@
+package instructions
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"realtime/pkg/logging"
+	"text/template"
+	"time"
+)
+
+// Vars are the variables available to instruction templates, along with
+// .Date and .Time which are filled in each time the template is rendered
+type Vars struct {
+	UserName string
+	Locale   string
+	Tools    []string          // Names of the tools available to the assistant
+	Extra    map[string]string // Any other values, e.g. {{index .Extra "company"}}
+}
+
+type templateData struct {
+	Vars
+	Date string
+	Time string
+}
+
+// Prompt renders assistant instructions from a text/template file
+type Prompt struct {
+	Path   string
+	Vars   Vars
+	Logger *slog.Logger // Logs reloads in Watch, defaults to a charm logger on stderr
+}
+
+// Render reads the template file and renders it with the prompt's variables
+func (p Prompt) Render() (string, error) {
+	text, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading prompt file: %w", err)
+	}
+
+	tmpl, err := template.New(p.Path).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return "", fmt.Errorf("error parsing prompt template: %w", err)
+	}
+
+	now := time.Now()
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData{
+		Vars: p.Vars,
+		Date: now.Format("Monday, January 2, 2006"),
+		Time: now.Format("15:04 MST"),
+	}); err != nil {
+		return "", fmt.Errorf("error rendering prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// Watch polls the template file every interval and calls onChange with the
+// re-rendered instructions whenever the file is modified, until done is closed.
+// Templates that fail to render are logged and skipped.
+func (p Prompt) Watch(interval time.Duration, done <-chan struct{}, onChange func(instructions string)) {
+	logger := logging.OrDefault(p.Logger).With("path", p.Path)
+
+	var lastModTime time.Time
+	if info, err := os.Stat(p.Path); err == nil {
+		lastModTime = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(p.Path)
+			if err != nil {
+				logger.Warn("Failed to check prompt file", "error", err)
+				continue
+			}
+			if info.ModTime().Equal(lastModTime) {
+				continue
+			}
+			lastModTime = info.ModTime()
+
+			instructions, err := p.Render()
+			if err != nil {
+				logger.Error("Failed to reload prompt", "error", err)
+				continue
+			}
+			logger.Info("Reloaded prompt")
+			onChange(instructions)
+		}
+	}
+}
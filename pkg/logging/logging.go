@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"realtime/pkg/pii"
+
+	"github.com/charmbracelet/log"
+)
+
+// Default returns the logger used when none is injected, which writes to
+// stderr in the same style as the rest of the app
+func Default() *slog.Logger {
+	return slog.New(NewRedactingHandler(log.NewWithOptions(os.Stderr, log.Options{
+		ReportCaller:    true,
+		ReportTimestamp: true,
+	})))
+}
+
+// OrDefault returns the injected logger with redaction applied, or the
+// default logger if logger is nil
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Default()
+	}
+	if _, ok := logger.Handler().(*RedactingHandler); ok {
+		return logger
+	}
+	return slog.New(NewRedactingHandler(logger.Handler()))
+}
+
+// RedactingHandler wraps a slog.Handler, redacting secrets and truncating
+// audio payloads in messages and attributes before they are logged
+type RedactingHandler struct {
+	handler slog.Handler
+	pii     *pii.Redactor
+}
+
+// NewRedactingHandler wraps handler with redaction
+func NewRedactingHandler(handler slog.Handler) *RedactingHandler {
+	return &RedactingHandler{handler: handler}
+}
+
+// WithPII returns a handler that also redacts personal data, such as emails
+// and phone numbers in transcripts and event dumps
+func (h *RedactingHandler) WithPII(redactor *pii.Redactor) *RedactingHandler {
+	return &RedactingHandler{handler: h.handler, pii: redactor}
+}
+
+// sanitize redacts secrets, payloads and personal data in s
+func (h *RedactingHandler) sanitize(s string) string {
+	return h.pii.Redact(Sanitize(s))
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.sanitize(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return &RedactingHandler{handler: h.handler.WithAttrs(redacted), pii: h.pii}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{handler: h.handler.WithGroup(name), pii: h.pii}
+}
+
+func (h *RedactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	if isSecretKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.sanitize(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		attrs := make([]any, len(group))
+		for i, a := range group {
+			attrs[i] = h.redactAttr(a)
+		}
+		return slog.Group(attr.Key, attrs...)
+	case slog.KindAny:
+		// Errors and other values are logged as their string form
+		return slog.String(attr.Key, h.sanitize(value.String()))
+	default:
+		return attr
+	}
+}
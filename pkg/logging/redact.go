@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// maxPayloadLength is the length above which base64 runs are assumed to be
+// audio payloads and truncated
+const maxPayloadLength = 128
+
+var (
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`),                   // OpenAI API keys
+		regexp.MustCompile(`AIza[0-9A-Za-z_\-]{30,}`),                  // Google API keys
+		regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-~+/=]{8,}`),   // Authorization headers
+		regexp.MustCompile(`(?i)([?&](?:key|api_key|token)=)[^&\s"]+`), // Keys in URLs
+	}
+	payloadPattern = regexp.MustCompile(fmt.Sprintf(`[A-Za-z0-9+/]{%d,}={0,2}`, maxPayloadLength))
+)
+
+// secretKeys are attribute keys whose values are always redacted
+var secretKeys = []string{"api_key", "apikey", "authorization", "password", "secret", "token"}
+
+// isSecretKey matches keys such as "token" and "openai_api_key" but not
+// "input_tokens"
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) || strings.HasSuffix(key, "-"+secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize redacts API keys and bearer tokens in s and replaces long base64
+// payloads, such as audio in event dumps, with their length
+func Sanitize(s string) string {
+	for _, pattern := range secretPatterns {
+		if pattern.NumSubexp() > 0 {
+			s = pattern.ReplaceAllString(s, "${1}"+redacted)
+		} else {
+			s = pattern.ReplaceAllString(s, redacted)
+		}
+	}
+	return payloadPattern.ReplaceAllStringFunc(s, func(payload string) string {
+		return fmt.Sprintf("<%d bytes base64>", len(payload))
+	})
+}
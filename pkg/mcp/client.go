@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"realtime/pkg/logging"
+	"realtime/pkg/provider"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Client is a connection to one MCP server
+type Client struct {
+	name      string
+	transport transport
+	logger    *slog.Logger
+	nextID    atomic.Int64
+}
+
+// Load reads a Config from a JSON file
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading MCP config: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("error parsing MCP config: %w", err)
+	}
+	return config, nil
+}
+
+// Connect launches or connects to the named server and initializes the session
+func Connect(ctx context.Context, name string, config ServerConfig, logger *slog.Logger) (*Client, error) {
+	logger = logging.OrDefault(logger).With("mcp_server", name)
+
+	var t transport
+	switch {
+	case config.Command != "":
+		stdio, err := newStdioTransport(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		t = stdio
+	case config.URL != "":
+		t = newHTTPTransport(config)
+	default:
+		return nil, fmt.Errorf("MCP server %s has neither a command nor a URL", name)
+	}
+
+	c := &Client{name: name, transport: t, logger: logger}
+
+	var result initializeResult
+	if err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: protocolVersion,
+		ClientInfo:      implementation{Name: "realtime", Version: "1.0.0"},
+	}, &result); err != nil {
+		t.close()
+		return nil, fmt.Errorf("error initializing MCP server %s: %w", name, err)
+	}
+	if err := t.notify(ctx, request{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		t.close()
+		return nil, fmt.Errorf("error initializing MCP server %s: %w", name, err)
+	}
+
+	logger.Info("Connected to MCP server", "server", result.ServerInfo.Name, "protocol_version", result.ProtocolVersion)
+	return c, nil
+}
+
+// ConnectAll connects to every server in the config, in name order. If any
+// server fails, those already connected are closed.
+func ConnectAll(ctx context.Context, config Config) ([]*Client, error) {
+	names := make([]string, 0, len(config.Servers))
+	for name := range config.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	clients := make([]*Client, 0, len(names))
+	for _, name := range names {
+		client, err := Connect(ctx, name, config.Servers[name], config.Logger)
+		if err != nil {
+			for _, client := range clients {
+				client.Close()
+			}
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// Name returns the name of the server in the config
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	id := c.nextID.Add(1)
+	resp, err := c.transport.call(ctx, request{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// ListTools returns all tools offered by the server
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, fmt.Errorf("error listing tools of MCP server %s: %w", c.name, err)
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls a tool and returns its text output. Tools that report an
+// error return it as an error, so the assistant can explain it to the user.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (string, error) {
+	var result callToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return "", fmt.Errorf("error calling %s on MCP server %s: %w", name, c.name, err)
+	}
+
+	var texts []string
+	for _, part := range result.Content {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		default:
+			// The realtime session only takes text outputs
+			texts = append(texts, fmt.Sprintf("[%s content omitted]", part.Type))
+		}
+	}
+	output := strings.Join(texts, "\n")
+
+	if result.IsError {
+		return "", errors.New(output)
+	}
+	return output, nil
+}
+
+// Tools lists the server's tools as provider tools, whose handlers call the
+// server, ready to register with any RealtimeProvider
+func (c *Client) Tools(ctx context.Context) ([]provider.Tool, error) {
+	mcpTools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := make([]provider.Tool, 0, len(mcpTools))
+	for _, mcpTool := range mcpTools {
+		name := mcpTool.Name
+		tools = append(tools, provider.Tool{
+			Name:        name,
+			Description: mcpTool.Description,
+			Parameters:  mcpTool.InputSchema,
+			Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+				return c.CallTool(ctx, name, arguments)
+			},
+		})
+	}
+	return tools, nil
+}
+
+// Close ends the session, stopping the server if it was launched over stdio
+func (c *Client) Close() error {
+	return c.transport.close()
+}
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// buildStandIn builds cmd/mcpstandin and returns the path of its binary
+func buildStandIn(t *testing.T) string {
+	t.Helper()
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool is needed to build the stand-in")
+	}
+	binary := filepath.Join(t.TempDir(), "mcpstandin")
+	if output, err := exec.Command(goTool, "build", "-o", binary, "realtime/cmd/mcpstandin").CombinedOutput(); err != nil {
+		t.Fatalf("building the stand-in: %v\n%s", err, output)
+	}
+	return binary
+}
+
+func TestStdio(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The stand-in refuses tools/ requests until it is sent notifications/initialized
+	client, err := Connect(ctx, "standin", ServerConfig{Command: buildStandIn(t)}, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if fmt.Sprint(names) != "[echo add fail]" {
+		t.Errorf("tools = %v, want [echo add fail]", names)
+	}
+
+	sum, err := client.CallTool(ctx, "add", json.RawMessage(`{"a":2,"b":3.5}`))
+	if err != nil || sum != "5.5" {
+		t.Errorf("add = %q, %v, want 5.5", sum, err)
+	}
+
+	if _, err := client.CallTool(ctx, "fail", json.RawMessage(`{}`)); err == nil || err.Error() != "the fail tool always fails" {
+		t.Errorf("fail returned error %v, want the tool's error", err)
+	}
+}
+
+// httpServer is a streamable HTTP MCP server that assigns a session on
+// initialize and answers tools/ requests with event streams
+type httpServer struct {
+	mu           sync.Mutex
+	initialized  bool
+	sessionEnded bool
+}
+
+func (s *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Method == "initialize" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-03-26","serverInfo":{"name":"test","version":"1"}}}`, req.ID)
+		return
+	}
+	if got := r.Header.Get("Mcp-Session-Id"); got != "session-1" {
+		http.Error(w, "unknown session "+got, http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete:
+		s.sessionEnded = true
+	case req.Method == "notifications/initialized":
+		s.initialized = true
+		w.WriteHeader(http.StatusAccepted)
+	case !s.initialized:
+		http.Error(w, req.Method+" before notifications/initialized", http.StatusBadRequest)
+	case req.Method == "tools/list":
+		w.Header().Set("Content-Type", "text/event-stream")
+		// A notification comes first, and the response is split over two data lines
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{}}\n\n")
+		fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%s,\ndata: \"result\":{\"tools\":[{\"name\":\"add\",\"inputSchema\":{}}]}}\n\n", req.ID)
+	case req.Method == "tools/call":
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"content\":[{\"type\":\"text\",\"text\":\"5\"}]}}\n\n", req.ID)
+	default:
+		http.Error(w, "unexpected "+req.Method, http.StatusBadRequest)
+	}
+}
+
+func TestStreamableHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	handler := &httpServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := Connect(ctx, "http", ServerConfig{URL: server.URL}, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Name != "add" {
+		t.Errorf("tools = %+v, want add", tools)
+	}
+	sum, err := client.CallTool(ctx, "add", json.RawMessage(`{"a":2,"b":3}`))
+	if err != nil || sum != "5" {
+		t.Errorf("add = %q, %v, want 5", sum, err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if !handler.sessionEnded {
+		t.Error("Close did not end the session")
+	}
+}
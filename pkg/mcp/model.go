@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// protocolVersion is the MCP revision spoken by the client
+const protocolVersion = "2025-03-26"
+
+// Config lists the MCP servers to use, in the mcpServers format used by
+// most MCP hosts:
+//
+//	{"mcpServers": {
+//	    "files": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-filesystem", "."]},
+//	    "search": {"url": "http://localhost:8000/mcp"}
+//	}}
+type Config struct {
+	Servers map[string]ServerConfig `json:"mcpServers"`
+	Logger  *slog.Logger            `json:"-"` // Defaults to a charm logger on stderr
+}
+
+// ServerConfig launches a server over stdio if Command is set, or connects
+// to it over streamable HTTP at URL
+type ServerConfig struct {
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"` // Added to the environment of the app
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // Sent with every HTTP request, e.g. Authorization
+}
+
+// JSON-RPC 2.0 request, or notification when ID is nil
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// JSON-RPC 2.0 response, or a request from the server when Method is set
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// initialize
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    struct{}       `json:"capabilities"`
+	ClientInfo      implementation `json:"clientInfo"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	ServerInfo      implementation `json:"serverInfo"`
+}
+
+type implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// tools/list
+type listToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type listToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Tool is a tool offered by an MCP server
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"` // JSON schema of the arguments
+}
+
+// tools/call
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type callToolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+type content struct {
+	Type     string `json:"type"` // text, image, audio or resource
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
@@ -0,0 +1,319 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// closeTimeout is how long a stdio server has to exit once its stdin is closed
+const closeTimeout = 5 * time.Second
+
+// transport carries JSON-RPC messages to and from a server
+type transport interface {
+	// call sends a request and waits for its response
+	call(ctx context.Context, req request) (response, error)
+	// notify sends a notification
+	notify(ctx context.Context, req request) error
+	close() error
+}
+
+// stdioTransport speaks newline-delimited JSON-RPC with a child process
+type stdioTransport struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	logger    *slog.Logger
+	writeMu   sync.Mutex
+	pending   map[string]chan response
+	pendingMu sync.Mutex
+	done      chan struct{}
+	err       error // Why the server exited, set before done is closed
+}
+
+func newStdioTransport(config ServerConfig, logger *slog.Logger) (*stdioTransport, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range config.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", config.Command, err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		logger:  logger,
+		pending: make(map[string]chan response),
+		done:    make(chan struct{}),
+	}
+	go t.readStderr(stderr)
+	go t.readMessages(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) readMessages(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg response
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.logger.Warn("Invalid message from MCP server", "error", err, "message", scanner.Text())
+			continue
+		}
+		if msg.Method != "" {
+			t.handleServerRequest(msg)
+			continue
+		}
+
+		t.pendingMu.Lock()
+		result, ok := t.pending[string(msg.ID)]
+		delete(t.pending, string(msg.ID))
+		t.pendingMu.Unlock()
+		if ok {
+			result <- msg
+		}
+	}
+
+	t.err = errors.New("MCP server exited")
+	if err := scanner.Err(); err != nil {
+		t.err = fmt.Errorf("error reading from MCP server: %w", err)
+	}
+	close(t.done)
+}
+
+// handleServerRequest answers pings, and tells the server that other
+// requests, e.g. for sampling, are not supported. Notifications are ignored.
+func (t *stdioTransport) handleServerRequest(msg response) {
+	if msg.ID == nil {
+		return
+	}
+	reply := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+	if msg.Method == "ping" {
+		reply["result"] = struct{}{}
+	} else {
+		reply["error"] = rpcError{Code: -32601, Message: "method not supported: " + msg.Method}
+	}
+	if err := t.write(reply); err != nil {
+		t.logger.Warn("Failed to reply to MCP server", "method", msg.Method, "error", err)
+	}
+}
+
+func (t *stdioTransport) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		t.logger.Debug("MCP server stderr", "line", scanner.Text())
+	}
+}
+
+func (t *stdioTransport) write(msg interface{}) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(payload, '\n'))
+	return err
+}
+
+func (t *stdioTransport) call(ctx context.Context, req request) (response, error) {
+	id := strconv.FormatInt(*req.ID, 10)
+	result := make(chan response, 1)
+	t.pendingMu.Lock()
+	t.pending[id] = result
+	t.pendingMu.Unlock()
+	defer func() {
+		t.pendingMu.Lock()
+		delete(t.pending, id)
+		t.pendingMu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return response{}, err
+	}
+
+	select {
+	case msg := <-result:
+		return msg, nil
+	case <-t.done:
+		return response{}, t.err
+	case <-ctx.Done():
+		return response{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, req request) error {
+	return t.write(req)
+}
+
+// close closes the server's stdin, which tells it to exit, and kills it if it does not
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(closeTimeout):
+		t.cmd.Process.Kill()
+	}
+	t.cmd.Wait()
+	return nil
+}
+
+// httpTransport speaks the streamable HTTP transport, where each message is
+// POSTed and responses come back as JSON or a server-sent event stream
+type httpTransport struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+	sessionID  string // Mcp-Session-Id assigned by the server on initialize
+	sessionMu  sync.Mutex
+}
+
+func newHTTPTransport(config ServerConfig) *httpTransport {
+	return &httpTransport{
+		url:        config.URL,
+		headers:    config.Headers,
+		httpClient: &http.Client{},
+	}
+}
+
+func (t *httpTransport) post(ctx context.Context, req request) (*http.Response, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	for key, value := range t.headers {
+		httpReq.Header.Set(key, value)
+	}
+	t.sessionMu.Lock()
+	if t.sessionID != "" {
+		httpReq.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.sessionMu.Unlock()
+
+	resp, err := t.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("MCP server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.sessionMu.Lock()
+		t.sessionID = sessionID
+		t.sessionMu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, req request) (response, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+
+	id := strconv.FormatInt(*req.ID, 10)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		var msg response
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			return response{}, fmt.Errorf("error decoding MCP response: %w", err)
+		}
+		return msg, nil
+	}
+
+	// The stream may carry server notifications before the response
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var msg response
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err == nil && msg.Method == "" && string(msg.ID) == id {
+			return msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return response{}, fmt.Errorf("error reading MCP event stream: %w", err)
+	}
+	return response{}, errors.New("MCP event stream ended without a response")
+}
+
+func (t *httpTransport) notify(ctx context.Context, req request) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close ends the session on the server, if it assigned one
+func (t *httpTransport) close() error {
+	t.sessionMu.Lock()
+	sessionID := t.sessionID
+	t.sessionMu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
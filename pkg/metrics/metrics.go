@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds all metrics of the assistant, along with the Go runtime and
+// process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	Connections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_connections_total",
+		Help: "Connection attempts to the assistant backend, by result (success or failure).",
+	}, []string{"backend", "result"})
+
+	Reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_reconnects_total",
+		Help: "Successful connections for a session ID that closed within the last 5 minutes.",
+	}, []string{"backend"})
+
+	ActiveSessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "realtime_active_sessions",
+		Help: "Sessions currently connected to the assistant backend.",
+	}, []string{"backend"})
+
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_events_received_total",
+		Help: "Server events received, by event type.",
+	}, []string{"backend", "type"})
+
+	EventsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_events_sent_total",
+		Help: "Client events sent, by event type.",
+	}, []string{"backend", "type"})
+
+	Errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_errors_total",
+		Help: "Errors reported by the assistant backend, by error code.",
+	}, []string{"backend", "code"})
+
+	ResponseLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "realtime_response_latency_seconds",
+		Help:    "Time from the end of the user's turn to the first audio of the response.",
+		Buckets: []float64{0.1, 0.2, 0.3, 0.5, 0.75, 1, 1.5, 2, 3, 5, 10},
+	}, []string{"backend"})
+
+	ResponseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "realtime_response_duration_seconds",
+		Help:    "Time from the start to the end of a response, by final status.",
+		Buckets: prometheus.ExponentialBuckets(0.25, 2, 8),
+	}, []string{"backend", "status"})
+
+	AudioBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_audio_bytes_total",
+		Help: "Audio bytes sent to (in) and received from (out) the assistant.",
+	}, []string{"backend", "direction"})
+
+	MicChannelFill = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "realtime_mic_channel_fill_ratio",
+		Help: "Fraction (0-1) of the microphone chunk channel in use.",
+	})
+
+	PlaybackUnderruns = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "realtime_playback_underruns_total",
+		Help: "Times playback ran out of audio while more of the response was still arriving.",
+	})
+
+	Tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_tokens_total",
+		Help: "Tokens used by responses, by direction (input or output) and modality (text or audio).",
+	}, []string{"backend", "direction", "modality"})
+
+	ToolCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "realtime_tool_call_duration_seconds",
+		Help:    "Duration of tool calls, by tool and result (success or error).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend", "tool", "result"})
+
+	GuardrailViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_guardrail_violations_total",
+		Help: "Transcripts that broke a guardrail, by direction (input or output) and rule.",
+	}, []string{"backend", "direction", "rule"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Connections,
+		Reconnects,
+		ActiveSessions,
+		EventsReceived,
+		EventsSent,
+		Errors,
+		ResponseLatency,
+		ResponseDuration,
+		AudioBytes,
+		MicChannelFill,
+		PlaybackUnderruns,
+		Tokens,
+		ToolCallDuration,
+		GuardrailViolations,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// reconnectWindow is how long after a named session closes that connecting
+// it again counts as a reconnect
+const reconnectWindow = 5 * time.Minute
+
+// closedSession is a named session that closed, remembered for reconnectWindow
+type closedSession struct {
+	id string
+	at time.Time
+}
+
+// closedSessions holds the explicit session IDs that closed within the
+// reconnect window, so that connecting one again counts as a reconnect.
+// Generated IDs are never reused, so they are not tracked.
+var closedSessions struct {
+	mu    sync.Mutex
+	at    map[string]time.Time
+	order []closedSession // Oldest first, to forget sessions after the window
+}
+
+// Connected records a connection attempt for the given backend and session,
+// which is active until Disconnected if it succeeded
+func Connected(backend, sessionID string, err error) {
+	if err != nil {
+		Connections.WithLabelValues(backend, "failure").Inc()
+		return
+	}
+	Connections.WithLabelValues(backend, "success").Inc()
+	ActiveSessions.WithLabelValues(backend).Inc()
+	if sessionID == "" {
+		return
+	}
+	closedSessions.mu.Lock()
+	defer closedSessions.mu.Unlock()
+	forgetClosedSessions(time.Now())
+	if _, ok := closedSessions.at[sessionID]; ok {
+		delete(closedSessions.at, sessionID)
+		Reconnects.WithLabelValues(backend).Inc()
+	}
+}
+
+// Disconnected records the end of a connected session
+func Disconnected(backend, sessionID string) {
+	ActiveSessions.WithLabelValues(backend).Dec()
+	if sessionID == "" {
+		return
+	}
+	now := time.Now()
+	closedSessions.mu.Lock()
+	defer closedSessions.mu.Unlock()
+	forgetClosedSessions(now)
+	if closedSessions.at == nil {
+		closedSessions.at = make(map[string]time.Time)
+	}
+	closedSessions.at[sessionID] = now
+	closedSessions.order = append(closedSessions.order, closedSession{id: sessionID, at: now})
+}
+
+// forgetClosedSessions drops the sessions that closed before the reconnect
+// window, closedSessions.mu must be held
+func forgetClosedSessions(now time.Time) {
+	n := 0
+	for _, closed := range closedSessions.order {
+		if now.Sub(closed.at) < reconnectWindow {
+			break
+		}
+		// The session may have closed again since, which is a later entry
+		if closedSessions.at[closed.id].Equal(closed.at) {
+			delete(closedSessions.at, closed.id)
+		}
+		n++
+	}
+	closedSessions.order = closedSessions.order[n:]
+}
+
+// ToolCalled records the duration of a tool call that started at start
+func ToolCalled(backend, tool string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	ToolCallDuration.WithLabelValues(backend, tool, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,207 @@
+package openairealtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"realtime/pkg/provider"
+	"regexp"
+	"strings"
+)
+
+// transferToolPrefix starts the names of the tools handing off to another agent
+const transferToolPrefix = "transfer_to_"
+
+// validAgentName matches names that can be part of a tool name
+var validAgentName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,50}$`)
+
+// Agent is a persona of the assistant, e.g. a receptionist or billing. The
+// assistant hands the conversation from one agent to another by calling a
+// generated transfer_to_<name> tool, which keeps the conversation but
+// changes the session to the new agent's config.
+type Agent struct {
+	Name         string   `json:"name"`         // Letters, digits, _ and -, e.g. billing
+	Description  string   `json:"description"`  // When to hand off to this agent, shown to the other agents
+	Instructions string   `json:"instructions"` // Defaults to the session instructions
+	Voice        string   `json:"voice"`        // Only applies before the assistant first speaks, the API keeps the voice of a session after that
+	Tools        []string `json:"tools"`        // Registered tools the agent can call, all of them if nil
+	Temperature  float64  `json:"temperature"`  // Defaults to the session temperature
+	HandoffTo    []string `json:"handoff_to"`   // Agents it can hand off to, all others if nil
+}
+
+// validateAgents checks that agent names are unique and usable in tool names
+// and that handoffs name known agents
+func validateAgents(agents []Agent) error {
+	names := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		if !validAgentName.MatchString(agent.Name) {
+			return fmt.Errorf("invalid agent name %q, use letters, digits, _ and -", agent.Name)
+		}
+		if names[agent.Name] {
+			return fmt.Errorf("agent %s is defined twice", agent.Name)
+		}
+		names[agent.Name] = true
+	}
+	for _, agent := range agents {
+		for _, target := range agent.HandoffTo {
+			if !names[target] {
+				return fmt.Errorf("agent %s hands off to unknown agent %s", agent.Name, target)
+			}
+		}
+	}
+	return nil
+}
+
+// canHandOff reports whether the agent can hand off to target
+func (a Agent) canHandOff(target string) bool {
+	if target == a.Name {
+		return false
+	}
+	if a.HandoffTo == nil {
+		return true
+	}
+	for _, name := range a.HandoffTo {
+		if name == target {
+			return true
+		}
+	}
+	return false
+}
+
+// canCall reports whether the agent can call a tool
+func (a Agent) canCall(tool string) bool {
+	if target, ok := strings.CutPrefix(tool, transferToolPrefix); ok {
+		return a.canHandOff(target)
+	}
+	if a.Tools == nil || tool == cancelTaskToolName {
+		return true
+	}
+	for _, name := range a.Tools {
+		if name == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// registerAgents declares a transfer tool for each agent and makes the first
+// agent active
+func (c *OpenAIRealtimeClient) registerAgents(agents []Agent) error {
+	if err := validateAgents(agents); err != nil {
+		return err
+	}
+	c.baseInstructions = c.session.Instructions
+	c.baseTemperature = c.session.Temperature
+	c.agents = make(map[string]Agent, len(agents))
+	for _, agent := range agents {
+		c.agents[agent.Name] = agent
+	}
+	if len(agents) > 1 {
+		for _, agent := range agents {
+			if err := c.RegisterTool(c.transferTool(agent)); err != nil {
+				return err
+			}
+		}
+	}
+	c.applyAgent(agents[0])
+	return nil
+}
+
+// applyAgent changes the session to the agent's config, sessionMu must not be held
+func (c *OpenAIRealtimeClient) applyAgent(agent Agent) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.agent = agent.Name
+	// Agents without their own settings get the session's, not the previous agent's
+	c.session.Instructions = c.baseInstructions
+	if agent.Instructions != "" {
+		c.session.Instructions = agent.Instructions
+	}
+	c.session.Temperature = c.baseTemperature
+	if agent.Temperature > 0 {
+		c.session.Temperature = agent.Temperature
+	}
+	if agent.Voice != "" {
+		if c.spoke.Load() {
+			c.logger.Warn("The voice cannot change once the assistant has spoken", "agent", agent.Name, "voice", agent.Voice)
+		} else {
+			c.session.Voice = agent.Voice
+		}
+	}
+}
+
+// availableTools filters the session tools to those the active agent can
+// call, sessionMu must be held
+func (c *OpenAIRealtimeClient) availableTools(tools []SessionTool) []SessionTool {
+	agent, ok := c.agents[c.agent]
+	if !ok {
+		return tools
+	}
+	available := make([]SessionTool, 0, len(tools))
+	for _, tool := range tools {
+		if agent.canCall(tool.Name) {
+			available = append(available, tool)
+		}
+	}
+	return available
+}
+
+// canCall reports whether the active agent can call a tool
+func (c *OpenAIRealtimeClient) canCall(tool string) bool {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	agent, ok := c.agents[c.agent]
+	return !ok || agent.canCall(tool)
+}
+
+// transferTool hands the conversation off to an agent
+func (c *OpenAIRealtimeClient) transferTool(agent Agent) provider.Tool {
+	description := fmt.Sprintf("Hand the conversation off to the %s agent", agent.Name)
+	if agent.Description != "" {
+		description += ": " + agent.Description
+	}
+	return provider.Tool{
+		Name:        transferToolPrefix + agent.Name,
+		Description: description,
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"reason":{"type":"string","description":"What the user needs, for the next agent"}}}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			if err := c.Handoff(agent.Name); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("You are now the %s agent. Carry on with the conversation without greeting the user again.", agent.Name), nil
+		},
+	}
+}
+
+// Agent returns the name of the active agent, empty without agents
+func (c *OpenAIRealtimeClient) Agent() string {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	return c.agent
+}
+
+// Handoff makes an agent active and updates the running session, keeping the conversation
+func (c *OpenAIRealtimeClient) Handoff(name string) error {
+	c.sessionMu.Lock()
+	agent, ok := c.agents[name]
+	previous := c.agent
+	c.sessionMu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown agent %s", name)
+	}
+	if name == previous {
+		return errors.New("already the active agent")
+	}
+
+	c.applyAgent(agent)
+	if c.started.Load() {
+		if err := c.sendSessionUpdate(); err != nil {
+			return err
+		}
+	}
+	c.logger.Info("Handed off", "from", previous, "to", name)
+	c.emit(provider.Event{Type: provider.Handoff, Text: name})
+	return nil
+}
@@ -0,0 +1,179 @@
+package openairealtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"realtime/pkg/provider"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// cancelTaskToolName is the tool registered with the first async tool, so the
+// assistant can cancel tasks the user no longer wants
+const cancelTaskToolName = "cancel_task"
+
+// idleResponseAttempts bounds how many responses createResponseWhenIdle
+// waits out before it gives up
+const idleResponseAttempts = 5
+
+// task is a running call of an async tool
+type task struct {
+	call    provider.ToolCall
+	cancel  context.CancelFunc
+	started time.Time
+}
+
+// Task describes a running call of an async tool
+type Task struct {
+	ID        string // The call ID, which the assistant uses to cancel it
+	Tool      string
+	Arguments json.RawMessage
+	Started   time.Time
+}
+
+// startTask runs an async tool in the background and returns the output
+// telling the assistant the call is in progress
+func (c *OpenAIRealtimeClient) startTask(tool provider.Tool, call provider.ToolCall) string {
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.tasksMu.Lock()
+	c.tasks[call.CallID] = &task{call: call, cancel: cancel, started: time.Now()}
+	c.tasksMu.Unlock()
+
+	go func() {
+		defer func() {
+			cancel()
+			c.tasksMu.Lock()
+			delete(c.tasks, call.CallID)
+			c.tasksMu.Unlock()
+		}()
+
+		output, _ := c.runHandler(ctx, tool, call)
+		if ctx.Err() != nil {
+			c.logger.Info("Task was cancelled", "tool", call.Name, "call_id", call.CallID)
+			return
+		}
+		c.announceTaskResult(call, output)
+	}()
+
+	output, _ := json.Marshal(map[string]string{
+		"status":  "in_progress",
+		"task_id": call.CallID,
+		"message": fmt.Sprintf("%s is running in the background. Its result will be sent to you when it is ready, "+
+			"so carry on with the conversation. If the user no longer wants it, call %s with this task_id.",
+			call.Name, cancelTaskToolName),
+	})
+	return string(output)
+}
+
+// announceTaskResult adds the output of a finished task to the conversation
+// and asks the assistant to tell the user about it
+func (c *OpenAIRealtimeClient) announceTaskResult(call provider.ToolCall, output string) {
+	c.logger.Info("Task finished", "tool", call.Name, "call_id", call.CallID)
+	if err := c.sendEvent(ConversationItemCreate{
+		EventID: uuid.NewString(),
+		Type:    "conversation.item.create",
+		Item: ConversationItem{
+			Type: "message",
+			Role: "system",
+			Content: []ContentPart{{
+				Type: "input_text",
+				Text: fmt.Sprintf("The background task %s (task_id %s) has finished with this result: %s\n"+
+					"Tell the user about it now.", call.Name, call.CallID, output),
+			}},
+		},
+	}); err != nil {
+		c.logger.Error("Error sending task result", "tool", call.Name, "call_id", call.CallID, "error", err)
+		return
+	}
+
+	if err := c.createResponseWhenIdle(ResponseOptions{}); err != nil {
+		c.logger.Error("Error announcing task result, the assistant will see it on its next response",
+			"tool", call.Name, "call_id", call.CallID, "error", err)
+	}
+}
+
+// createResponseWhenIdle creates a response once the response in progress,
+// if any, is done, since only one response can be active at a time
+func (c *OpenAIRealtimeClient) createResponseWhenIdle(opts ResponseOptions) error {
+	for i := 0; i < idleResponseAttempts; i++ {
+		responseDone := c.nextResponseDone()
+		_, err := c.CreateResponse(opts)
+		if err == nil || !strings.HasPrefix(err.Error(), "conversation_already_has_active_response") {
+			return err
+		}
+		select {
+		case <-responseDone:
+		case <-c.done:
+			return errors.New("client is closed")
+		}
+	}
+	return errors.New("gave up waiting for the responses in progress to finish")
+}
+
+// nextResponseDone returns a channel that is closed when the next response is done
+func (c *OpenAIRealtimeClient) nextResponseDone() <-chan struct{} {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	return c.responseDone
+}
+
+// signalResponseDone wakes createResponseWhenIdle calls waiting for the active response to finish
+func (c *OpenAIRealtimeClient) signalResponseDone() {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	close(c.responseDone)
+	c.responseDone = make(chan struct{})
+}
+
+// Tasks returns the running calls of async tools
+func (c *OpenAIRealtimeClient) Tasks() []Task {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	tasks := make([]Task, 0, len(c.tasks))
+	for _, t := range c.tasks {
+		tasks = append(tasks, Task{ID: t.call.CallID, Tool: t.call.Name, Arguments: t.call.Arguments, Started: t.started})
+	}
+	return tasks
+}
+
+// CancelTask cancels a running call of an async tool. Its result is dropped.
+func (c *OpenAIRealtimeClient) CancelTask(id string) error {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	t, ok := c.tasks[id]
+	if !ok {
+		return fmt.Errorf("no running task %s", id)
+	}
+	t.cancel()
+	delete(c.tasks, id)
+	return nil
+}
+
+// cancelTaskTool lets the assistant cancel tasks when the user says never mind
+func (c *OpenAIRealtimeClient) cancelTaskTool() provider.Tool {
+	return provider.Tool{
+		Name:        cancelTaskToolName,
+		Description: "Cancel a background task that is still running, e.g. when the user says never mind",
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"task_id":{"type":"string","description":"task_id returned when the task was started"}},"required":["task_id"]}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				TaskID string `json:"task_id"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if args.TaskID == "" {
+				return "", errors.New("task_id is required")
+			}
+			if err := c.CancelTask(args.TaskID); err != nil {
+				return "", err
+			}
+			return "The task was cancelled", nil
+		},
+	}
+}
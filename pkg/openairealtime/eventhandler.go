@@ -3,20 +3,200 @@ package openairealtime
 import (
 	"encoding/base64"
 	"encoding/json"
-
-	"github.com/charmbracelet/log"
+	"realtime/pkg/metrics"
+	"realtime/pkg/provider"
 )
 
-// handleResponseAudioDelta handles the response audio delta event
+// OnEvent registers a handler for events from the assistant
+func (c *OpenAIRealtimeClient) OnEvent(handler provider.EventHandler) {
+	c.eventHandlersMu.Lock()
+	defer c.eventHandlersMu.Unlock()
+	c.eventHandlers = append(c.eventHandlers, handler)
+}
+
+// emit sends an event to all registered handlers
+func (c *OpenAIRealtimeClient) emit(event provider.Event) {
+	c.eventHandlersMu.Lock()
+	handlers := c.eventHandlers
+	c.eventHandlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// RawEventHandler receives a server event the client does not handle, e.g.
+// one added to the API after this client was written, as it was received
+type RawEventHandler func(eventType string, message json.RawMessage)
+
+// OnRawEvent registers a handler for the server events the client does not
+// handle, which are otherwise only logged
+func (c *OpenAIRealtimeClient) OnRawEvent(handler RawEventHandler) {
+	c.eventHandlersMu.Lock()
+	defer c.eventHandlersMu.Unlock()
+	c.rawEventHandlers = append(c.rawEventHandlers, handler)
+}
+
+// emitRaw sends an unhandled event to all registered raw handlers and
+// reports whether there were any
+func (c *OpenAIRealtimeClient) emitRaw(eventType string, message []byte) bool {
+	c.eventHandlersMu.Lock()
+	handlers := c.rawEventHandlers
+	c.eventHandlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(eventType, message)
+	}
+	return len(handlers) > 0
+}
+
+// handleResponseAudioDelta handles the response audio delta event. It runs
+// on the event loop, so chunks reach the audio output in the order they were
+// received.
 func handleResponseAudioDelta(client *OpenAIRealtimeClient, b []byte) {
 	audioDelta := ResponseAudioDelta{}
 	json.Unmarshal(b, &audioDelta)
 
 	delta, err := base64.StdEncoding.DecodeString(audioDelta.Delta)
 	if err != nil {
-		log.Printf("Error decoding base64 audio delta: %v", err)
+		client.logger.Error("Error decoding base64 audio delta", "response_id", audioDelta.ResponseID, "error", err)
+		return
+	}
+	if !client.guardAudio(audioDelta.ResponseID, audioDelta.ItemID, len(delta)) {
 		return
 	}
 
-	client.audioOutput <- delta
+	client.metrics.responseAudio(len(delta))
+	client.trace.responseAudio(audioDelta.ResponseID, audioDelta.ItemID, len(delta))
+	client.latency.responseAudio(audioDelta.ResponseID)
+	select {
+	case client.audioOutput <- provider.AudioChunk{
+		ResponseID:   audioDelta.ResponseID,
+		ItemID:       audioDelta.ItemID,
+		ContentIndex: audioDelta.ContentIndex,
+		Audio:        delta,
+	}:
+	case <-client.done:
+	}
+}
+
+// handleSpeech handles the speech started and stopped events
+func handleSpeech(client *OpenAIRealtimeClient, b []byte, eventType provider.EventType) {
+	speech := InputAudioBufferSpeech{}
+	json.Unmarshal(b, &speech)
+
+	switch eventType {
+	case provider.SpeechStarted:
+		client.trace.speechStarted(speech.ItemID)
+	case provider.SpeechStopped:
+		client.metrics.turnEnded()
+		client.trace.speechStopped(speech.ItemID)
+		client.latency.speechEnded()
+	}
+	client.emit(provider.Event{Type: eventType, ItemID: speech.ItemID})
+}
+
+// handleInputAudioTranscriptionDelta handles part of the transcript of the user's speech
+func handleInputAudioTranscriptionDelta(client *OpenAIRealtimeClient, b []byte) {
+	delta := InputAudioTranscriptionDelta{}
+	json.Unmarshal(b, &delta)
+
+	client.emit(provider.Event{
+		Type:   provider.InputTranscriptDelta,
+		ItemID: delta.ItemID,
+		Text:   delta.Delta,
+	})
+}
+
+// handleInputAudioTranscriptionFailed handles the failure to transcribe the user's speech
+func handleInputAudioTranscriptionFailed(client *OpenAIRealtimeClient, b []byte) {
+	failed := InputAudioTranscriptionFailed{}
+	json.Unmarshal(b, &failed)
+
+	client.logger.Warn("Input audio transcription failed", "item_id", failed.ItemID,
+		"code", failed.Error.Code, "error", failed.Error.Message)
+	client.metrics.errors.Add(1)
+	metrics.Errors.WithLabelValues(metricsBackend, "transcription_failed").Inc()
+
+	client.emit(provider.Event{
+		Type:   provider.InputTranscriptFailed,
+		ItemID: failed.ItemID,
+		Text:   failed.Error.Message,
+	})
+}
+
+// handleInputAudioTranscriptionCompleted handles the transcript of the user's speech
+func handleInputAudioTranscriptionCompleted(client *OpenAIRealtimeClient, b []byte) {
+	transcription := InputAudioTranscriptionCompleted{}
+	json.Unmarshal(b, &transcription)
+
+	if client.guardrails.Input != nil {
+		go client.checkInput(transcription.ItemID, transcription.Transcript)
+	}
+	client.emit(provider.Event{
+		Type:   provider.InputTranscript,
+		ItemID: transcription.ItemID,
+		Text:   transcription.Transcript,
+	})
+}
+
+// handleResponseTranscriptDelta handles part of the transcript of the assistant's response
+func handleResponseTranscriptDelta(client *OpenAIRealtimeClient, b []byte) {
+	delta := ResponseTranscriptDelta{}
+	json.Unmarshal(b, &delta)
+	if !client.guardTranscript(delta.ResponseID, delta.ItemID, delta.Delta) {
+		return
+	}
+
+	client.emit(provider.Event{
+		Type:       provider.TranscriptDelta,
+		ResponseID: delta.ResponseID,
+		ItemID:     delta.ItemID,
+		Text:       delta.Delta,
+	})
+}
+
+// handleResponseTranscriptDone handles the full transcript of the assistant's response
+func handleResponseTranscriptDone(client *OpenAIRealtimeClient, b []byte) {
+	done := ResponseTranscriptDone{}
+	json.Unmarshal(b, &done)
+	if client.guardBlocked(done.ResponseID) {
+		return
+	}
+
+	text := done.Transcript
+	if text == "" {
+		text = done.Text
+	}
+	client.emit(provider.Event{
+		Type:       provider.Transcript,
+		ResponseID: done.ResponseID,
+		ItemID:     done.ItemID,
+		Text:       text,
+	})
+}
+
+// handleResponseDone handles the end of a response and runs any tools it called
+func handleResponseDone(client *OpenAIRealtimeClient, b []byte) {
+	done := ResponseDone{}
+	json.Unmarshal(b, &done)
+	client.metrics.responseDone(done)
+
+	client.toolCallsMu.Lock()
+	toolCalls := len(client.toolCalls[done.Response.ID]) > 0
+	client.toolCallsMu.Unlock()
+	client.sessionMu.Lock()
+	outputFormat := client.session.OutputAudioFormat
+	client.sessionMu.Unlock()
+	client.trace.responseDone(done.Response.ID, done.Response.Status, outputFormat, toolCalls)
+
+	eventType := provider.ResponseDone
+	if done.Response.Status == "cancelled" {
+		eventType = provider.ResponseInterrupted
+	}
+	client.emit(provider.Event{Type: eventType, ResponseID: done.Response.ID})
+
+	client.guardResponseDone(done.Response.ID)
+	go client.runToolCalls(done.Response.ID)
+	client.signalResponseDone()
 }
@@ -0,0 +1,276 @@
+package openairealtime
+
+import (
+	"fmt"
+	"realtime/pkg/metrics"
+	"realtime/pkg/provider"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// guardState tracks responses for the guardrails
+type guardState struct {
+	mu        sync.Mutex
+	current   string                      // Response in progress
+	responses map[string]*guardedResponse // By response ID, while the response or its check runs
+	blocked   map[string]bool             // Responses cut off by a guardrail, whose output is dropped
+	blockedAt []string                    // Blocked response IDs, oldest first, to bound blocked
+}
+
+// maxBlocked is how many blocked responses are remembered to drop their late output
+const maxBlocked = 64
+
+// guardedResponse is the output of a response so far
+type guardedResponse struct {
+	itemID     string
+	text       strings.Builder
+	audioBytes int
+	checking   bool // A check is running
+	dirty      bool // The text grew during the check, so it runs again
+	done       bool
+}
+
+func newGuardState() guardState {
+	return guardState{
+		responses: make(map[string]*guardedResponse),
+		blocked:   make(map[string]bool),
+	}
+}
+
+// response returns the state of a response, creating it, g.mu must be held
+func (g *guardState) response(responseID string) *guardedResponse {
+	r, ok := g.responses[responseID]
+	if !ok {
+		r = &guardedResponse{}
+		g.responses[responseID] = r
+	}
+	return r
+}
+
+// endCheck ends the check of a response, forgetting the response if it
+// finished during the check, g.mu must be held
+func (g *guardState) endCheck(responseID string, r *guardedResponse) {
+	r.checking = false
+	if r.done {
+		delete(g.responses, responseID)
+	}
+}
+
+// guardResponseStarted records the response in progress
+func (c *OpenAIRealtimeClient) guardResponseStarted(responseID string) {
+	c.guard.mu.Lock()
+	defer c.guard.mu.Unlock()
+	c.guard.current = responseID
+}
+
+// guardResponseDone forgets a finished response unless it is still being checked
+func (c *OpenAIRealtimeClient) guardResponseDone(responseID string) {
+	c.guard.mu.Lock()
+	defer c.guard.mu.Unlock()
+	if c.guard.current == responseID {
+		c.guard.current = ""
+	}
+	if r, ok := c.guard.responses[responseID]; ok && r.checking {
+		r.done = true
+		return
+	}
+	delete(c.guard.responses, responseID)
+}
+
+// guardBlocked reports whether a response was cut off by a guardrail
+func (c *OpenAIRealtimeClient) guardBlocked(responseID string) bool {
+	c.guard.mu.Lock()
+	defer c.guard.mu.Unlock()
+	return c.guard.blocked[responseID]
+}
+
+// guardAudio records audio of a response and reports whether it may be played
+func (c *OpenAIRealtimeClient) guardAudio(responseID, itemID string, n int) bool {
+	c.guard.mu.Lock()
+	defer c.guard.mu.Unlock()
+	if c.guard.blocked[responseID] {
+		return false
+	}
+	if c.guardrails.Output != nil {
+		r := c.guard.response(responseID)
+		r.itemID = itemID
+		r.audioBytes += n
+	}
+	return true
+}
+
+// guardTranscript checks the transcript of a response as it grows and
+// reports whether the delta may be shown
+func (c *OpenAIRealtimeClient) guardTranscript(responseID, itemID, delta string) bool {
+	c.guard.mu.Lock()
+	defer c.guard.mu.Unlock()
+	if c.guard.blocked[responseID] {
+		return false
+	}
+	if c.guardrails.Output == nil {
+		return true
+	}
+
+	r := c.guard.response(responseID)
+	r.itemID = itemID
+	r.text.WriteString(delta)
+	if r.checking {
+		r.dirty = true
+	} else {
+		r.checking = true
+		go c.checkOutput(responseID)
+	}
+	return true
+}
+
+// checkOutput checks the transcript of a response until it stops growing or
+// breaks the policy. Checks run off the event loop, so classifiers can be slow.
+func (c *OpenAIRealtimeClient) checkOutput(responseID string) {
+	for {
+		c.guard.mu.Lock()
+		r := c.guard.responses[responseID]
+		text := r.text.String()
+		r.dirty = false
+		c.guard.mu.Unlock()
+
+		violation, err := c.guardrails.Output.Check(c.ctx, text)
+		if err != nil {
+			c.logger.Error("Output guardrail failed", "response_id", responseID, "error", err)
+		}
+
+		c.guard.mu.Lock()
+		if violation != nil && !c.guard.blocked[responseID] {
+			itemID := r.itemID
+			c.guard.mu.Unlock()
+			c.logger.Warn("Response broke a guardrail", "response_id", responseID, "rule", violation.Rule, "reason", violation.Reason)
+			metrics.GuardrailViolations.WithLabelValues(metricsBackend, "output", violation.Rule).Inc()
+			c.cutOff(responseID)
+			c.guard.mu.Lock()
+			c.guard.endCheck(responseID, r)
+			c.guard.mu.Unlock()
+			c.emit(provider.Event{Type: provider.GuardrailTripped, ResponseID: responseID, ItemID: itemID, Text: violation.String()})
+			c.sayGuardrailReply()
+			return
+		}
+		if violation != nil || !r.dirty {
+			c.guard.endCheck(responseID, r)
+			c.guard.mu.Unlock()
+			return
+		}
+		c.guard.mu.Unlock()
+	}
+}
+
+// checkInput checks the transcript of the user's speech
+func (c *OpenAIRealtimeClient) checkInput(itemID, transcript string) {
+	violation, err := c.guardrails.Input.Check(c.ctx, transcript)
+	if err != nil {
+		c.logger.Error("Input guardrail failed", "item_id", itemID, "error", err)
+		return
+	}
+	if violation == nil {
+		return
+	}
+	c.logger.Warn("User input broke a guardrail", "item_id", itemID, "rule", violation.Rule, "reason", violation.Reason)
+	metrics.GuardrailViolations.WithLabelValues(metricsBackend, "input", violation.Rule).Inc()
+
+	// Remove the input so the assistant does not act on it, and stop the answer to it
+	if err := c.sendEvent(ConversationItemDelete{
+		EventID: uuid.NewString(),
+		Type:    "conversation.item.delete",
+		ItemID:  itemID,
+	}); err != nil {
+		c.logger.Error("Error deleting input item", "item_id", itemID, "error", err)
+	}
+	c.guard.mu.Lock()
+	current := c.guard.current
+	c.guard.mu.Unlock()
+	if current != "" {
+		c.cutOff(current)
+	}
+	c.emit(provider.Event{Type: provider.GuardrailTripped, ItemID: itemID, Text: violation.String()})
+	c.sayGuardrailReply()
+}
+
+// cutOff cancels a response, discards its pending audio and truncates it to
+// what the user heard
+func (c *OpenAIRealtimeClient) cutOff(responseID string) {
+	c.guard.mu.Lock()
+	if c.guard.blocked[responseID] {
+		c.guard.mu.Unlock()
+		return
+	}
+	c.guard.blocked[responseID] = true
+	c.guard.blockedAt = append(c.guard.blockedAt, responseID)
+	if len(c.guard.blockedAt) > maxBlocked {
+		delete(c.guard.blocked, c.guard.blockedAt[0])
+		c.guard.blockedAt = c.guard.blockedAt[1:]
+	}
+	active := c.guard.current == responseID
+	var itemID string
+	var audioBytes int
+	if r, ok := c.guard.responses[responseID]; ok {
+		itemID, audioBytes = r.itemID, r.audioBytes
+	}
+	c.guard.mu.Unlock()
+
+	if active {
+		if err := c.sendEvent(ResponseCancel{
+			EventID:    uuid.NewString(),
+			Type:       "response.cancel",
+			ResponseID: responseID,
+		}); err != nil {
+			c.logger.Error("Error cancelling response", "response_id", responseID, "error", err)
+		}
+	}
+
+	var pending time.Duration
+	if flusher, ok := c.playback.(PlaybackFlusher); ok {
+		pending = flusher.Flush()
+	}
+
+	if itemID == "" {
+		return
+	}
+	if audioBytes == 0 {
+		// A text response has no audio to truncate, so it is removed
+		if err := c.sendEvent(ConversationItemDelete{
+			EventID: uuid.NewString(),
+			Type:    "conversation.item.delete",
+			ItemID:  itemID,
+		}); err != nil {
+			c.logger.Error("Error deleting response item", "item_id", itemID, "error", err)
+		}
+		return
+	}
+	c.sessionMu.Lock()
+	outputFormat := c.session.OutputAudioFormat
+	c.sessionMu.Unlock()
+	heard := max(outputFormat.Duration(audioBytes)-pending, 0)
+	if err := c.sendEvent(ConversationItemTruncate{
+		EventID:    uuid.NewString(),
+		Type:       "conversation.item.truncate",
+		ItemID:     itemID,
+		AudioEndMS: int(heard.Milliseconds()),
+	}); err != nil {
+		c.logger.Error("Error truncating response item", "item_id", itemID, "error", err)
+	}
+}
+
+// sayGuardrailReply has the assistant say the canned reply, if there is one
+func (c *OpenAIRealtimeClient) sayGuardrailReply() {
+	if c.guardrails.Reply == "" {
+		return
+	}
+	go func() {
+		if err := c.createResponseWhenIdle(ResponseOptions{
+			Instructions: fmt.Sprintf("Say exactly this and nothing else: %s", c.guardrails.Reply),
+			ToolChoice:   &ToolChoice{Mode: "none"},
+		}); err != nil {
+			c.logger.Error("Error saying guardrail reply", "error", err)
+		}
+	}()
+}
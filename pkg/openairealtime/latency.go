@@ -0,0 +1,199 @@
+package openairealtime
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLatencyHistory is the number of turns kept for Latencies and LatencyReport
+const maxLatencyHistory = 1000
+
+// TurnLatency breaks down the time from the end of the user's speech to the
+// user hearing the response, the delay users actually feel
+type TurnLatency struct {
+	ResponseID       string
+	SpeechEnd        time.Time // speech_stopped received, or EndTurn called with ManualTurnDetection
+	Committed        time.Time // Input audio committed, by the client or the server
+	ResponseCreated  time.Time // response.created received
+	FirstAudioDelta  time.Time // First response.audio.delta received
+	FirstAudioPlayed time.Time // First non-silent sample played, zero without a PlaybackStartNotifier
+}
+
+// Total returns the time from the end of the user's speech to the first audio
+// being played, or received if playback is not monitored
+func (l TurnLatency) Total() time.Duration {
+	if l.FirstAudioPlayed.IsZero() {
+		return l.FirstAudioDelta.Sub(l.SpeechEnd)
+	}
+	return l.FirstAudioPlayed.Sub(l.SpeechEnd)
+}
+
+// Commit returns the time from the end of the user's speech to the commit
+func (l TurnLatency) Commit() time.Duration {
+	return l.Committed.Sub(l.SpeechEnd)
+}
+
+// Creation returns the time from the commit to the response being created
+func (l TurnLatency) Creation() time.Duration {
+	return l.ResponseCreated.Sub(l.Committed)
+}
+
+// FirstDelta returns the time from the response being created to its first audio
+func (l TurnLatency) FirstDelta() time.Duration {
+	return l.FirstAudioDelta.Sub(l.ResponseCreated)
+}
+
+// Playout returns the time from the first audio being received to it being
+// played, zero if playback is not monitored
+func (l TurnLatency) Playout() time.Duration {
+	if l.FirstAudioPlayed.IsZero() {
+		return 0
+	}
+	return l.FirstAudioPlayed.Sub(l.FirstAudioDelta)
+}
+
+// Percentiles summarizes a latency across turns
+type Percentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// LatencyReport summarizes the latency of the turns of a session
+type LatencyReport struct {
+	Turns      int
+	Total      Percentiles
+	Commit     Percentiles
+	Creation   Percentiles
+	FirstDelta Percentiles
+	Playout    Percentiles
+}
+
+// PlaybackStartNotifier is implemented by playback monitors that can report
+// when the user starts hearing each response, such as audiooutput.StreamHandler
+type PlaybackStartNotifier interface {
+	OnPlaybackStart(f func(at time.Time))
+}
+
+// latencyTracker timestamps the stages of each turn
+type latencyTracker struct {
+	mu      sync.Mutex
+	logger  *slog.Logger
+	pending *TurnLatency // The turn waiting for its response to be heard
+	waitFor bool         // Wait for playback before completing the turn
+	history []TurnLatency
+}
+
+// speechEnded starts measuring a turn
+func (t *latencyTracker) speechEnded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = &TurnLatency{SpeechEnd: time.Now()}
+}
+
+// committed records the commit of the turn's audio, whichever of the client
+// and server commits it first
+func (t *latencyTracker) committed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending != nil && t.pending.Committed.IsZero() {
+		t.pending.Committed = time.Now()
+	}
+}
+
+// responseCreated records the creation of the first response of the turn
+func (t *latencyTracker) responseCreated(responseID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil || t.pending.ResponseID != "" {
+		return
+	}
+	if t.pending.Committed.IsZero() {
+		t.pending.Committed = time.Now()
+	}
+	t.pending.ResponseID = responseID
+	t.pending.ResponseCreated = time.Now()
+}
+
+// responseAudio records the first audio of the turn's response
+func (t *latencyTracker) responseAudio(responseID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil || t.pending.ResponseID != responseID || !t.pending.FirstAudioDelta.IsZero() {
+		return
+	}
+	t.pending.FirstAudioDelta = time.Now()
+	if !t.waitFor {
+		t.complete()
+	}
+}
+
+// playbackStarted records the first audio of the turn's response being played
+func (t *latencyTracker) playbackStarted(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil || t.pending.FirstAudioDelta.IsZero() {
+		return
+	}
+	t.pending.FirstAudioPlayed = at
+	t.complete()
+}
+
+func (t *latencyTracker) complete() {
+	l := *t.pending
+	t.pending = nil
+
+	t.history = append(t.history, l)
+	if len(t.history) > maxLatencyHistory {
+		t.history = t.history[len(t.history)-maxLatencyHistory:]
+	}
+
+	t.logger.Info("Turn latency",
+		"response_id", l.ResponseID,
+		"total_ms", l.Total().Milliseconds(),
+		"commit_ms", l.Commit().Milliseconds(),
+		"creation_ms", l.Creation().Milliseconds(),
+		"first_delta_ms", l.FirstDelta().Milliseconds(),
+		"playout_ms", l.Playout().Milliseconds(),
+	)
+}
+
+// Latencies returns the latency of the most recent turns, oldest first
+func (c *OpenAIRealtimeClient) Latencies() []TurnLatency {
+	c.latency.mu.Lock()
+	defer c.latency.mu.Unlock()
+	return append([]TurnLatency(nil), c.latency.history...)
+}
+
+// LatencyReport summarizes the latency of the most recent turns
+func (c *OpenAIRealtimeClient) LatencyReport() LatencyReport {
+	turns := c.Latencies()
+	return LatencyReport{
+		Turns:      len(turns),
+		Total:      percentiles(turns, TurnLatency.Total),
+		Commit:     percentiles(turns, TurnLatency.Commit),
+		Creation:   percentiles(turns, TurnLatency.Creation),
+		FirstDelta: percentiles(turns, TurnLatency.FirstDelta),
+		Playout:    percentiles(turns, TurnLatency.Playout),
+	}
+}
+
+func percentiles(turns []TurnLatency, stage func(TurnLatency) time.Duration) Percentiles {
+	if len(turns) == 0 {
+		return Percentiles{}
+	}
+	durations := make([]time.Duration, len(turns))
+	for i, turn := range turns {
+		durations[i] = stage(turn)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	// Nearest-rank percentile
+	at := func(p int) time.Duration {
+		rank := (p*len(durations) + 99) / 100
+		return durations[rank-1]
+	}
+	return Percentiles{P50: at(50), P90: at(90), P99: at(99)}
+}
@@ -0,0 +1,186 @@
+package openairealtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTooManySessions is returned when the SessionManager is at its session limit
+var ErrTooManySessions = errors.New("too many concurrent sessions")
+
+// ManagerConfig holds configuration for a SessionManager
+type ManagerConfig struct {
+	MaxSessions int           // Maximum number of concurrent sessions, 0 for no limit
+	IdleTimeout time.Duration // Sessions without activity for this long are closed, 0 to never reap
+}
+
+// SessionManager creates, tracks and tears down many independent clients
+type SessionManager struct {
+	config   ManagerConfig
+	mu       sync.Mutex
+	sessions map[string]*OpenAIRealtimeClient
+	pending  int             // Sessions being connected, counted towards MaxSessions
+	reserved map[string]bool // IDs of the named sessions being connected
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewSessionManager creates a SessionManager and starts reaping idle sessions
+func NewSessionManager(config ManagerConfig) *SessionManager {
+	m := &SessionManager{
+		config:   config,
+		sessions: make(map[string]*OpenAIRealtimeClient),
+		reserved: make(map[string]bool),
+		done:     make(chan struct{}),
+	}
+
+	if config.IdleTimeout > 0 {
+		go m.reapIdleSessions()
+	}
+
+	return m
+}
+
+// Create connects a new client with its own config and tracks it until it is closed.
+// The caller attaches audio to the client and starts it as usual.
+func (m *SessionManager) Create(config Config) (*OpenAIRealtimeClient, error) {
+	m.mu.Lock()
+	select {
+	case <-m.done:
+		m.mu.Unlock()
+		return nil, errors.New("session manager is closed")
+	default:
+	}
+	if config.SessionID != "" {
+		if _, ok := m.sessions[config.SessionID]; ok || m.reserved[config.SessionID] {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("session %s already exists", config.SessionID)
+		}
+	}
+	if m.config.MaxSessions > 0 && len(m.sessions)+m.pending >= m.config.MaxSessions {
+		m.mu.Unlock()
+		return nil, ErrTooManySessions
+	}
+	// Reserve the ID, so concurrent calls cannot connect the same session twice
+	m.pending++
+	if config.SessionID != "" {
+		m.reserved[config.SessionID] = true
+	}
+	m.mu.Unlock()
+
+	client, err := GetOpenAIRealtimeClient(config)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending--
+	delete(m.reserved, config.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	// The manager may have been closed while the client was connecting
+	select {
+	case <-m.done:
+		client.Close()
+		return nil, errors.New("session manager is closed")
+	default:
+	}
+	m.sessions[client.ID()] = client
+
+	// Stop tracking the session once it is closed, whoever closes it
+	go func() {
+		<-client.Done()
+		m.mu.Lock()
+		if m.sessions[client.ID()] == client {
+			delete(m.sessions, client.ID())
+		}
+		m.mu.Unlock()
+	}()
+
+	return client, nil
+}
+
+// Get returns the client with the given session ID
+func (m *SessionManager) Get(id string) (*OpenAIRealtimeClient, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client, ok := m.sessions[id]
+	return client, ok
+}
+
+// Sessions returns the IDs of all open sessions
+func (m *SessionManager) Sessions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Len returns the number of open sessions
+func (m *SessionManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// CloseSession closes the client with the given session ID
+func (m *SessionManager) CloseSession(id string) error {
+	client, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("session %s not found", id)
+	}
+	return client.Close()
+}
+
+// Close closes all sessions and stops reaping, no sessions can be created afterwards
+func (m *SessionManager) Close() error {
+	m.mu.Lock()
+	m.once.Do(func() { close(m.done) })
+	clients := make([]*OpenAIRealtimeClient, 0, len(m.sessions))
+	for _, client := range m.sessions {
+		clients = append(clients, client)
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, client := range clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", client.ID(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *SessionManager) reapIdleSessions() {
+	interval := m.config.IdleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			var idle []*OpenAIRealtimeClient
+			for _, client := range m.sessions {
+				if now.Sub(client.Metrics().LastActivity) > m.config.IdleTimeout {
+					idle = append(idle, client)
+				}
+			}
+			m.mu.Unlock()
+
+			for _, client := range idle {
+				client.logger.Info("Closing idle session")
+				client.Close()
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package openairealtime
+
+import (
+	"realtime/pkg/metrics"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of the activity of a client
+type Metrics struct {
+	EventsReceived int64
+	EventsSent     int64
+	AudioBytesIn   int64 // Microphone audio sent to the assistant
+	AudioBytesOut  int64 // Assistant audio received
+	Errors         int64
+	ConnectedAt    time.Time
+	LastActivity   time.Time
+}
+
+type clientMetrics struct {
+	eventsReceived atomic.Int64
+	eventsSent     atomic.Int64
+	audioBytesIn   atomic.Int64
+	audioBytesOut  atomic.Int64
+	errors         atomic.Int64
+	connectedAt    time.Time
+	lastActivity   atomic.Int64 // Unix nanoseconds
+
+	// Response timing, exported to Prometheus
+	turnEndedAt      atomic.Int64 // Unix nanoseconds when the last user turn ended, 0 once answered
+	responseStarts   map[string]time.Time
+	responseStartsMu sync.Mutex
+}
+
+// touch records activity on the client, idle clients are reaped by the SessionManager
+func (m *clientMetrics) touch() {
+	m.lastActivity.Store(time.Now().UnixNano())
+}
+
+// turnEnded records the end of a user turn, the response latency is measured from here
+func (m *clientMetrics) turnEnded() {
+	m.turnEndedAt.Store(time.Now().UnixNano())
+}
+
+// responseAudio records audio of a response, observing the response latency
+// on the first audio after a user turn
+func (m *clientMetrics) responseAudio(bytes int) {
+	m.audioBytesOut.Add(int64(bytes))
+	metrics.AudioBytes.WithLabelValues(metricsBackend, "out").Add(float64(bytes))
+
+	if ended := m.turnEndedAt.Swap(0); ended != 0 {
+		metrics.ResponseLatency.WithLabelValues(metricsBackend).Observe(time.Since(time.Unix(0, ended)).Seconds())
+	}
+}
+
+// responseStarted records the start of a response
+func (m *clientMetrics) responseStarted(id string) {
+	m.responseStartsMu.Lock()
+	defer m.responseStartsMu.Unlock()
+	if m.responseStarts == nil {
+		m.responseStarts = make(map[string]time.Time)
+	}
+	m.responseStarts[id] = time.Now()
+}
+
+// responseDone observes the duration and token usage of a finished response
+func (m *clientMetrics) responseDone(done ResponseDone) {
+	m.responseStartsMu.Lock()
+	start, ok := m.responseStarts[done.Response.ID]
+	delete(m.responseStarts, done.Response.ID)
+	m.responseStartsMu.Unlock()
+	if ok {
+		metrics.ResponseDuration.WithLabelValues(metricsBackend, done.Response.Status).Observe(time.Since(start).Seconds())
+	}
+
+	usage := done.Response.Usage
+	metrics.Tokens.WithLabelValues(metricsBackend, "input", "text").Add(float64(usage.InputTokenDetails.TextTokens))
+	metrics.Tokens.WithLabelValues(metricsBackend, "input", "audio").Add(float64(usage.InputTokenDetails.AudioTokens))
+	metrics.Tokens.WithLabelValues(metricsBackend, "output", "text").Add(float64(usage.OutputTokenDetails.TextTokens))
+	metrics.Tokens.WithLabelValues(metricsBackend, "output", "audio").Add(float64(usage.OutputTokenDetails.AudioTokens))
+}
+
+// metricsSessionID returns the session ID used to count reconnects, generated
+// IDs are unique so they are not counted
+func (c *OpenAIRealtimeClient) metricsSessionID() string {
+	if c.namedSession {
+		return c.id
+	}
+	return ""
+}
+
+// eventType returns the Type field of a client event
+func eventType(event interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct {
+		return "unknown"
+	}
+	if field := v.FieldByName("Type"); field.Kind() == reflect.String {
+		return field.String()
+	}
+	return "unknown"
+}
+
+// Metrics returns a snapshot of the client's activity
+func (c *OpenAIRealtimeClient) Metrics() Metrics {
+	return Metrics{
+		EventsReceived: c.metrics.eventsReceived.Load(),
+		EventsSent:     c.metrics.eventsSent.Load(),
+		AudioBytesIn:   c.metrics.audioBytesIn.Load(),
+		AudioBytesOut:  c.metrics.audioBytesOut.Load(),
+		Errors:         c.metrics.errors.Load(),
+		ConnectedAt:    c.metrics.connectedAt,
+		LastActivity:   time.Unix(0, c.metrics.lastActivity.Load()),
+	}
+}
@@ -1,29 +1,68 @@
 package openairealtime
 
+import (
+	"encoding/json"
+	"log/slog"
+	"realtime/pkg/approval"
+	"realtime/pkg/audioformat"
+	"realtime/pkg/guardrail"
+	"realtime/pkg/pii"
+	"time"
+)
+
 // session.update
 type SessionUpdate struct {
-	EventID string `json:"event_id"`
-	Type    string `json:"type"`
-	Session struct {
-		Modalities              []string `json:"modalities"`
-		Instructions            string   `json:"instructions"`
-		Voice                   string   `json:"voice"`
-		InputAudioFormat        string   `json:"input_audio_format"`
-		OutputAudioFormat       string   `json:"output_audio_format"`
-		InputAudioTranscription struct {
-			Model string `json:"model"`
-		} `json:"input_audio_transcription"`
-		TurnDetection struct {
-			Type              string  `json:"type"`
-			Threshold         float64 `json:"threshold"`
-			PrefixPaddingMS   int     `json:"prefix_padding_ms"`
-			SilenceDurationMS int     `json:"silence_duration_ms"`
-			CreateResponse    bool    `json:"create_response"`
-		} `json:"turn_detection"`
-		ToolChoice              string  `json:"tool_choice"`
-		Temperature             float64 `json:"temperature"`
-		MaxResponseOutputTokens string  `json:"max_response_output_tokens"`
-	} `json:"session"`
+	EventID string  `json:"event_id"`
+	Type    string  `json:"type"`
+	Session Session `json:"session"`
+}
+
+// Session is the session configuration sent with session.update
+type Session struct {
+	Modalities               []string                 `json:"modalities"`
+	Instructions             string                   `json:"instructions"`
+	Voice                    string                   `json:"voice"`
+	InputAudioFormat         audioformat.Format       `json:"input_audio_format"`
+	OutputAudioFormat        audioformat.Format       `json:"output_audio_format"`
+	InputAudioTranscription  *InputAudioTranscription `json:"input_audio_transcription"`   // nil disables transcription
+	TurnDetection            *TurnDetection           `json:"turn_detection"`              // nil disables turn detection
+	InputAudioNoiseReduction *NoiseReduction          `json:"input_audio_noise_reduction"` // nil disables noise reduction
+	Tools                    []SessionTool            `json:"tools"`
+	ToolChoice               string                   `json:"tool_choice"`
+	Temperature              float64                  `json:"temperature"`
+	MaxResponseOutputTokens  string                   `json:"max_response_output_tokens"`
+}
+
+// InputAudioTranscription configures transcription of the user's speech
+type InputAudioTranscription struct {
+	Model    string `json:"model"`
+	Language string `json:"language,omitempty"` // ISO-639-1 code, e.g. en
+	Prompt   string `json:"prompt,omitempty"`   // Vocabulary or context to bias the transcription
+}
+
+// NoiseReduction filters the input audio before VAD and the model hear it
+type NoiseReduction struct {
+	Type NoiseReductionMode `json:"type"`
+}
+
+// TurnDetection configures server-side voice activity detection
+type TurnDetection struct {
+	Type              string    `json:"type"`
+	Threshold         float64   `json:"threshold,omitempty"`           // server_vad only
+	PrefixPaddingMS   int       `json:"prefix_padding_ms,omitempty"`   // server_vad only
+	SilenceDurationMS int       `json:"silence_duration_ms,omitempty"` // server_vad only
+	IdleTimeoutMS     int       `json:"idle_timeout_ms,omitempty"`     // server_vad only
+	Eagerness         Eagerness `json:"eagerness,omitempty"`           // semantic_vad only
+	CreateResponse    bool      `json:"create_response"`
+	InterruptResponse bool      `json:"interrupt_response"`
+}
+
+// SessionTool declares a function the assistant can call
+type SessionTool struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"` // JSON schema of the arguments
 }
 
 // input_audio_buffer.append
@@ -33,6 +72,241 @@ type InputAudioBufferAppend struct {
 	Audio   string `json:"audio"` // base64 encoded audio
 }
 
+// input_audio_buffer.commit
+type InputAudioBufferCommit struct {
+	EventID string `json:"event_id"`
+	Type    string `json:"type"`
+}
+
+// input_audio_buffer.clear
+type InputAudioBufferClear struct {
+	EventID string `json:"event_id"`
+	Type    string `json:"type"`
+}
+
+// conversation.item.create
+type ConversationItemCreate struct {
+	EventID string           `json:"event_id"`
+	Type    string           `json:"type"`
+	Item    ConversationItem `json:"item"`
+}
+
+// ConversationItem is an item of the conversation, such as a message or a function call output
+type ConversationItem struct {
+	ID      string        `json:"id,omitempty"`
+	Type    string        `json:"type"` // message, function_call or function_call_output
+	Role    string        `json:"role,omitempty"`
+	Content []ContentPart `json:"content,omitempty"`
+	CallID  string        `json:"call_id,omitempty"`
+	Output  string        `json:"output,omitempty"`
+}
+
+// ContentPart is part of the content of a message item
+type ContentPart struct {
+	Type string `json:"type"` // input_text, input_audio, text or audio
+	Text string `json:"text,omitempty"`
+}
+
+// response.create
+type ResponseCreate struct {
+	EventID  string           `json:"event_id"`
+	Type     string           `json:"type"`
+	Response *ResponseOptions `json:"response,omitempty"`
+}
+
+// ResponseOptions overrides the session defaults for a single response,
+// zero values keep the session defaults
+type ResponseOptions struct {
+	Instructions    string            `json:"instructions,omitempty"`
+	Voice           string            `json:"voice,omitempty"`
+	Modalities      []string          `json:"modalities,omitempty"` // e.g. []string{"text"} for a text-only answer
+	ToolChoice      *ToolChoice       `json:"tool_choice,omitempty"`
+	Temperature     *float64          `json:"temperature,omitempty"`
+	MaxOutputTokens int               `json:"max_output_tokens,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// ToolChoice is how the model uses tools in a response: auto, none or
+// required, or a call to the named function
+type ToolChoice struct {
+	Mode     string // auto, none or required
+	Function string // Name of the function to call, overrides Mode
+}
+
+// MarshalJSON encodes a mode as a string and a function as
+// {"type":"function","name":...}
+func (t ToolChoice) MarshalJSON() ([]byte, error) {
+	if t.Function != "" {
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+		}{Type: "function", Name: t.Function})
+	}
+	return json.Marshal(t.Mode)
+}
+
+// response.created
+type ResponseCreated struct {
+	EventID  string `json:"event_id"`
+	Type     string `json:"type"`
+	Response struct {
+		ID       string            `json:"id"`
+		Status   string            `json:"status"`
+		Metadata map[string]string `json:"metadata"`
+	} `json:"response"`
+}
+
+// response.done
+type ResponseDone struct {
+	EventID  string `json:"event_id"`
+	Type     string `json:"type"`
+	Response struct {
+		ID     string `json:"id"`
+		Status string `json:"status"` // completed, cancelled, failed or incomplete
+		Usage  Usage  `json:"usage"`
+	} `json:"response"`
+}
+
+// Usage is the token usage of a response
+type Usage struct {
+	TotalTokens       int `json:"total_tokens"`
+	InputTokens       int `json:"input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	InputTokenDetails struct {
+		TextTokens   int `json:"text_tokens"`
+		AudioTokens  int `json:"audio_tokens"`
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_token_details"`
+	OutputTokenDetails struct {
+		TextTokens  int `json:"text_tokens"`
+		AudioTokens int `json:"audio_tokens"`
+	} `json:"output_token_details"`
+}
+
+// input_audio_buffer.speech_started, input_audio_buffer.speech_stopped
+type InputAudioBufferSpeech struct {
+	EventID      string `json:"event_id"`
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	AudioStartMS int    `json:"audio_start_ms"`
+	AudioEndMS   int    `json:"audio_end_ms"`
+}
+
+// input_audio_buffer.committed
+type InputAudioBufferCommitted struct {
+	EventID        string `json:"event_id"`
+	Type           string `json:"type"`
+	PreviousItemID string `json:"previous_item_id"`
+	ItemID         string `json:"item_id"`
+}
+
+// response.cancel
+type ResponseCancel struct {
+	EventID    string `json:"event_id"`
+	Type       string `json:"type"`
+	ResponseID string `json:"response_id,omitempty"` // Defaults to the response in progress
+}
+
+// conversation.item.truncate
+type ConversationItemTruncate struct {
+	EventID      string `json:"event_id"`
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	ContentIndex int    `json:"content_index"`
+	AudioEndMS   int    `json:"audio_end_ms"` // Audio the user heard, the rest and its transcript are removed
+}
+
+// conversation.item.delete
+type ConversationItemDelete struct {
+	EventID string `json:"event_id"`
+	Type    string `json:"type"`
+	ItemID  string `json:"item_id"`
+}
+
+// conversation.item.truncated
+type ConversationItemTruncated struct {
+	EventID      string `json:"event_id"`
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	ContentIndex int    `json:"content_index"`
+	AudioEndMS   int    `json:"audio_end_ms"`
+}
+
+// conversation.item.input_audio_transcription.completed
+type InputAudioTranscriptionCompleted struct {
+	EventID      string `json:"event_id"`
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	ContentIndex int    `json:"content_index"`
+	Transcript   string `json:"transcript"`
+}
+
+// conversation.item.input_audio_transcription.delta
+type InputAudioTranscriptionDelta struct {
+	EventID      string `json:"event_id"`
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	ContentIndex int    `json:"content_index"`
+	Delta        string `json:"delta"`
+}
+
+// conversation.item.input_audio_transcription.failed
+type InputAudioTranscriptionFailed struct {
+	EventID      string `json:"event_id"`
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	ContentIndex int    `json:"content_index"`
+	Error        struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Param   string `json:"param"`
+	} `json:"error"`
+}
+
+// response.audio_transcript.delta, response.text.delta
+type ResponseTranscriptDelta struct {
+	EventID    string `json:"event_id"`
+	Type       string `json:"type"`
+	ResponseID string `json:"response_id"`
+	ItemID     string `json:"item_id"`
+	Delta      string `json:"delta"`
+}
+
+// response.audio_transcript.done, response.text.done
+type ResponseTranscriptDone struct {
+	EventID    string `json:"event_id"`
+	Type       string `json:"type"`
+	ResponseID string `json:"response_id"`
+	ItemID     string `json:"item_id"`
+	Transcript string `json:"transcript"` // response.audio_transcript.done
+	Text       string `json:"text"`       // response.text.done
+}
+
+// response.function_call_arguments.done
+type ResponseFunctionCallArgumentsDone struct {
+	EventID    string `json:"event_id"`
+	Type       string `json:"type"`
+	ResponseID string `json:"response_id"`
+	ItemID     string `json:"item_id"`
+	CallID     string `json:"call_id"`
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+}
+
+// error
+type ErrorEvent struct {
+	EventID string `json:"event_id"`
+	Type    string `json:"type"`
+	Error   struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Param   string `json:"param"`
+		EventID string `json:"event_id"` // The client event that caused the error
+	} `json:"error"`
+}
+
 // response.audio.delta
 type ResponseAudioDelta struct {
 	EventID      string `json:"event_id"`
@@ -45,5 +319,69 @@ type ResponseAudioDelta struct {
 }
 
 type Config struct {
-	APIKey string
+	APIKey         string
+	Protocol       Protocol          // Defaults to ProtocolBeta
+	URL            string            // Defaults to the Realtime API endpoint, e.g. a local mock server for testing
+	Model          string            // Defaults to gpt-4o-realtime-preview-2024-12-17 with ProtocolBeta and gpt-realtime with ProtocolGA
+	KeyLogFile     string            // Appends the TLS session keys to this file for debugging with e.g. Wireshark, off if empty
+	SessionID      string            // Identifies the client in logs and the SessionManager, generated if empty
+	LogFields      []interface{}     // Extra key/value pairs added to every log line of the client
+	Logger         *slog.Logger      // Defaults to a charm logger on stderr, secrets are always redacted
+	Approval       *approval.Gate    // Decides on calls of tools that require confirmation, which are rejected without it
+	Instructions   string            // Assistant instructions, defaults to a general purpose assistant
+	DuplexMode     DuplexMode        // Defaults to HalfDuplex
+	EchoGateRatio  float64           // Mic/playback RMS ratio for EchoGated mode, defaults to 2.0
+	TurnDetection  TurnDetectionMode // Defaults to ServerVAD
+	VAD            VADOptions        // Tuning for ServerVAD and SemanticVAD
+	Transcription  TranscriptionOptions
+	NoiseReduction NoiseReductionMode // Defaults to NoNoiseReduction
+	Agents         []Agent            // Agents the assistant hands off between, starting with the first
+	Guardrails     GuardrailOptions   // Policies enforced on the transcripts of both sides
+	ModelPII       *pii.Redactor      // Redacts personal data in text and tool outputs sent to the model, optional
+
+	InputAudioFormat  audioformat.Format // Format of the attached audio input, defaults to PCM16
+	OutputAudioFormat audioformat.Format // Format of the attached audio output, defaults to PCM16
+}
+
+// VADOptions tunes voice activity detection, zero values use the defaults
+type VADOptions struct {
+	Threshold                float64   // Speech probability threshold for ServerVAD (0-1)
+	PrefixPaddingMS          int       // Audio kept before detected speech for ServerVAD
+	SilenceDurationMS        int       // Silence that ends a turn for ServerVAD
+	IdleTimeoutMS            int       // Prompt the assistant to respond after this much idle time with ServerVAD
+	Eagerness                Eagerness // How quickly SemanticVAD ends a turn
+	DisableInterruptResponse bool      // Keep responding when the user starts talking
+}
+
+// TranscriptionOptions configures transcription of the user's speech, zero
+// values use the defaults
+type TranscriptionOptions struct {
+	Disabled bool   // Turn off transcription, no InputTranscript events are sent
+	Model    string // Defaults to whisper-1, e.g. gpt-4o-transcribe or gpt-4o-mini-transcribe
+	Language string // ISO-639-1 code of the user's language, improves accuracy and latency
+	Prompt   string // Vocabulary, e.g. product names, or context to bias the transcription
+}
+
+// PlaybackMonitor reports on the audio currently being played to the user
+type PlaybackMonitor interface {
+	// Level returns the RMS level (0-1) of the most recently played audio
+	Level() float64
+}
+
+// PlaybackFlusher is implemented by playback monitors that can discard the
+// audio they have not played yet, such as audiooutput.StreamHandler
+type PlaybackFlusher interface {
+	// Flush discards pending audio and returns how long it would have played
+	Flush() time.Duration
+}
+
+// GuardrailOptions enforces policies on what is said. A response breaking
+// the Output policy is cancelled, its pending audio is discarded and it is
+// truncated to what the user heard. A user transcript breaking the Input
+// policy is removed from the conversation and the response to it is cut off
+// the same way.
+type GuardrailOptions struct {
+	Output guardrail.Checker // Checks the assistant's transcript as it streams
+	Input  guardrail.Checker // Checks the transcripts of the user's speech
+	Reply  string            // Said instead of a blocked response, e.g. "Sorry, I can't help with that."
 }
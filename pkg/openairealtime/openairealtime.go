@@ -1,36 +1,87 @@
 package openairealtime
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"realtime/pkg/approval"
+	"realtime/pkg/audioformat"
+	"realtime/pkg/logging"
+	"realtime/pkg/metrics"
+	"realtime/pkg/pii"
+	"realtime/pkg/provider"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/charmbracelet/log"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
 )
 
-var logger = log.NewWithOptions(os.Stderr, log.Options{
-	ReportCaller:    true,
-	ReportTimestamp: true,
-})
+var _ provider.RealtimeProvider = (*OpenAIRealtimeClient)(nil)
 
 type OpenAIRealtimeClient struct {
+	id                 string
+	namedSession       bool // The session ID was given in the config, so connecting it again is a reconnect
+	apikey             string
+	protocol           Protocol
+	url                string // Endpoint without the model parameter
+	keyLogFile         string
+	model              string
 	conn               *websocket.Conn
-	audioOutput        chan<- []byte
+	logger             *slog.Logger
+	approval           *approval.Gate
+	metrics            clientMetrics
+	trace              *turnTrace
+	latency            latencyTracker
+	done               chan struct{}
+	closeOnce          sync.Once
+	audioOutput        chan<- provider.AudioChunk
 	audioInput         <-chan []byte
 	assistantIsTalking bool
+	duplexMode         DuplexMode
+	echoGateRatio      float64
+	echoGateOpenUntil  time.Time
+	playback           PlaybackMonitor
+	session            Session
+	sessionMu          sync.Mutex
+	turnEnds           chan chan error                // EndTurn requests, handled in order with the audio input
+	pendingResponses   map[string]chan responseResult // CreateResponse calls by event ID
+	pendingMu          sync.Mutex
+	writeMu            sync.Mutex // websocket connections support one concurrent writer
+	tools              map[string]provider.Tool
+	agents             map[string]Agent               // Agents by name, guarded by sessionMu
+	agent              string                         // Name of the active agent
+	baseInstructions   string                         // Session instructions of agents without their own
+	baseTemperature    float64                        // Session temperature of agents without their own
+	spoke              atomic.Bool                    // The assistant has sent audio, so the voice is fixed
+	toolCalls          map[string][]provider.ToolCall // Tool calls by response ID, run when the response is done
+	toolCallsMu        sync.Mutex
+	tasks              map[string]*task // Running calls of async tools by call ID
+	responseDone       chan struct{}    // Closed and replaced when a response is done
+	tasksMu            sync.Mutex
+	guardrails         GuardrailOptions
+	guard              guardState
+	modelPII           *pii.Redactor
+	eventHandlers      []provider.EventHandler
+	rawEventHandlers   []RawEventHandler
+	eventHandlersMu    sync.Mutex
+	started            atomic.Bool
+	ctx                context.Context // Cancelled when the client is closed
+	cancel             context.CancelFunc
 }
 
 // AttachAudioOutput attaches an audio output channel for assistant -> client communication
-func (c *OpenAIRealtimeClient) AttachAudioOutput(output chan<- []byte) {
+func (c *OpenAIRealtimeClient) AttachAudioOutput(output chan<- provider.AudioChunk) {
 	c.audioOutput = output
 }
 
@@ -39,16 +90,45 @@ func (c *OpenAIRealtimeClient) AttachAudioInput(input <-chan []byte) {
 	c.audioInput = input
 }
 
-// GetOpenAIRealtimeClient initializes a new OpenAI Realtime client
+// AttachPlaybackMonitor attaches a monitor of the audio being played back, required for EchoGated mode.
+// Monitors implementing PlaybackStartNotifier also time when the user hears each response.
+func (c *OpenAIRealtimeClient) AttachPlaybackMonitor(monitor PlaybackMonitor) {
+	c.playback = monitor
+	if notifier, ok := monitor.(PlaybackStartNotifier); ok {
+		c.latency.mu.Lock()
+		c.latency.waitFor = true
+		c.latency.mu.Unlock()
+		notifier.OnPlaybackStart(c.latency.playbackStarted)
+	}
+}
+
+// GetOpenAIRealtimeClient initializes a new OpenAI Realtime client and connects it
 func GetOpenAIRealtimeClient(config Config) (*OpenAIRealtimeClient, error) {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		logger.Errorf("Error loading .env file: %v", err)
-		return nil, fmt.Errorf("error loading .env file: %w", err)
+	client, err := NewOpenAIRealtimeClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewOpenAIRealtimeClient initializes a new OpenAI Realtime client without connecting it
+func NewOpenAIRealtimeClient(config Config) (*OpenAIRealtimeClient, error) {
+	id := config.SessionID
+	if id == "" {
+		id = uuid.NewString()
 	}
+	logger := logging.OrDefault(config.Logger).With(append([]interface{}{"session_id", id}, config.LogFields...)...)
 
 	var apikey string
 	if config.APIKey == "" {
+		// Load environment variables
+		if err := godotenv.Load(); err != nil && os.Getenv("OPENAI_API_KEY") == "" {
+			logger.Error("Error loading .env file", "error", err)
+			return nil, fmt.Errorf("error loading .env file: %w", err)
+		}
 		apikey = os.Getenv("OPENAI_API_KEY")
 	} else {
 		apikey = config.APIKey
@@ -58,60 +138,186 @@ func GetOpenAIRealtimeClient(config Config) (*OpenAIRealtimeClient, error) {
 		return nil, errors.New("OPENAI_API_KEY is not set")
 	}
 
-	// Open the key log file for writing
-	keyLogFile, err := os.Create("keylogfile.log")
+	protocol := config.Protocol
+	model := config.Model
+	switch protocol {
+	case "", ProtocolBeta:
+		protocol = ProtocolBeta
+		if model == "" {
+			model = defaultBetaModel
+		}
+	case ProtocolGA:
+		if model == "" {
+			model = defaultGAModel
+		}
+	default:
+		return nil, fmt.Errorf("unknown protocol: %q", protocol)
+	}
+	endpoint := config.URL
+	if endpoint == "" {
+		endpoint = defaultURL
+	}
+
+	duplexMode := config.DuplexMode
+	switch duplexMode {
+	case "":
+		duplexMode = HalfDuplex
+	case HalfDuplex, FullDuplex, EchoGated:
+	default:
+		return nil, fmt.Errorf("unknown duplex mode: %q", duplexMode)
+	}
+
+	echoGateRatio := config.EchoGateRatio
+	if echoGateRatio <= 0 {
+		echoGateRatio = defaultEchoGateRatio
+	}
+
+	session, err := newSession(config)
 	if err != nil {
-		log.Fatalf("Failed to create key log file: %v", err)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := &OpenAIRealtimeClient{
+		id:               id,
+		namedSession:     config.SessionID != "",
+		apikey:           apikey,
+		protocol:         protocol,
+		url:              endpoint,
+		keyLogFile:       config.KeyLogFile,
+		model:            model,
+		logger:           logger,
+		approval:         config.Approval,
+		trace:            newTurnTrace(id),
+		latency:          latencyTracker{logger: logger},
+		done:             make(chan struct{}),
+		duplexMode:       duplexMode,
+		echoGateRatio:    echoGateRatio,
+		session:          session,
+		turnEnds:         make(chan chan error),
+		pendingResponses: make(map[string]chan responseResult),
+		tools:            make(map[string]provider.Tool),
+		toolCalls:        make(map[string][]provider.ToolCall),
+		tasks:            make(map[string]*task),
+		responseDone:     make(chan struct{}),
+		guardrails:       config.Guardrails,
+		guard:            newGuardState(),
+		modelPII:         config.ModelPII,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+	if len(config.Agents) > 0 {
+		if err := client.registerAgents(config.Agents); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Connect opens the WebSocket connection to the OpenAI Realtime API
+func (c *OpenAIRealtimeClient) Connect(ctx context.Context) error {
+	if c.conn != nil {
+		return errors.New("client is already connected")
 	}
-	defer keyLogFile.Close()
 
-	// Configure TLS with KeyLogWriter
-	tlsConfig := &tls.Config{
-		KeyLogWriter: keyLogFile,
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
 	}
+	query := u.Query()
+	query.Set("model", c.model)
+	u.RawQuery = query.Encode()
 
-	url := "wss://api.openai.com/v1/realtime?model=gpt-4o-realtime-preview-2024-12-17"
 	dialer := websocket.Dialer{
 		// HandshakeTimeout:  45 * time.Second,
 		EnableCompression: true, // Try disabling compression if you're having issues
 		ReadBufferSize:    1024 * 1024,
 		WriteBufferSize:   1024 * 1024,
-		TLSClientConfig:   tlsConfig,
+	}
+	if c.keyLogFile != "" {
+		// The keys decrypt the whole session, so they are only written when asked for
+		keyLog, err := os.OpenFile(c.keyLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open key log file: %w", err)
+		}
+		defer keyLog.Close()
+		c.logger.Warn("Writing TLS session keys", "path", c.keyLogFile)
+		dialer.TLSClientConfig = &tls.Config{KeyLogWriter: keyLog}
 	}
 	headers := http.Header{
-		"Authorization":            []string{"Bearer " + apikey},
-		"OpenAI-Beta":              []string{"realtime=v1"},
+		"Authorization":            []string{"Bearer " + c.apikey},
 		"Sec-WebSocket-Extensions": []string{"-permessage-deflate"},
 	}
+	if c.protocol == ProtocolBeta {
+		headers.Set("OpenAI-Beta", "realtime=v1")
+	}
 
-	conn, resp, err := dialer.Dial(url, headers)
+	conn, resp, err := dialer.DialContext(ctx, u.String(), headers)
+	metrics.Connected(metricsBackend, c.metricsSessionID(), err)
 	if err != nil {
-		logger.Printf("WebSocket dial error: %v", err)
 		if resp != nil {
-			logger.Printf("HTTP Response: %d", resp.StatusCode)
+			c.logger.Error("WebSocket dial error", "error", err, "status", resp.StatusCode)
+		} else {
+			c.logger.Error("WebSocket dial error", "error", err)
 		}
-		return nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
+		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
-	client := &OpenAIRealtimeClient{conn: conn}
+	c.conn = conn
+	c.metrics.connectedAt = time.Now()
+	c.metrics.touch()
 
-	// go client.listenForEvents()
+	return nil
+}
 
-	return client, nil
+// ID returns the session ID of the client
+func (c *OpenAIRealtimeClient) ID() string {
+	return c.id
+}
+
+// Done returns a channel that is closed when the client is closed
+func (c *OpenAIRealtimeClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection to the assistant and stops the client
+func (c *OpenAIRealtimeClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cancel()
+		c.trace.close()
+		if c.conn != nil {
+			err = c.conn.Close()
+			metrics.Disconnected(metricsBackend, c.metricsSessionID())
+		}
+		c.logger.Info("Session closed")
+	})
+	return err
 }
 
+// Start sends the session config and streams audio until the client is closed
 func (c *OpenAIRealtimeClient) Start() error {
+	if c.conn == nil {
+		return errors.New("client is not connected")
+	}
 	if c.audioOutput == nil {
 		return errors.New("audio output channel is not attached")
 	}
 	if c.audioInput == nil {
 		return errors.New("audio input channel is not attached")
 	}
+	if c.duplexMode == EchoGated && c.playback == nil {
+		return errors.New("playback monitor is not attached, required for echo-gated duplex mode")
+	}
 
 	// Send initial session config
-	if err := sendInitialSessionConfig(c.conn); err != nil {
+	if err := c.sendSessionUpdate(); err != nil {
 		return fmt.Errorf("failed to send initial session config: %w", err)
 	}
+	c.started.Store(true)
 
 	// Start pinger
 	// go c.startPinger()
@@ -120,11 +326,12 @@ func (c *OpenAIRealtimeClient) Start() error {
 	// Start listening for audio input from client
 	go c.listenForAudioInput()
 
-	select {}
+	<-c.done
+	return nil
 }
 
 func (c *OpenAIRealtimeClient) listenForEvents() {
-	defer c.conn.Close()
+	defer c.Close()
 
 	// Set read deadline to detect stale connections
 	c.conn.SetReadDeadline(time.Now().Add(time.Second * 60))
@@ -132,7 +339,7 @@ func (c *OpenAIRealtimeClient) listenForEvents() {
 	// Set ping handler to keep connection alive
 	c.conn.SetPingHandler(func(appData string) error {
 		// Log bytes in hex format for better debugging
-		logger.Infof("Received ping bytes: %x", []byte(appData))
+		c.logger.Debug("Received ping", "data", fmt.Sprintf("%x", appData))
 		c.conn.SetReadDeadline(time.Now().Add(time.Second * 60))
 
 		// Send pong with the same data we received
@@ -141,7 +348,7 @@ func (c *OpenAIRealtimeClient) listenForEvents() {
 
 	// Set pong handler to log round-trip latency or extend the connection lifespan
 	c.conn.SetPongHandler(func(appData string) error {
-		logger.Printf("Received pong: %s", appData)
+		c.logger.Debug("Received pong", "data", appData)
 		c.conn.SetReadDeadline(time.Now().Add(time.Second * 60))
 		return nil
 	})
@@ -149,107 +356,468 @@ func (c *OpenAIRealtimeClient) listenForEvents() {
 	for {
 		messageType, r, err := c.conn.NextReader()
 		if err != nil {
-			logger.Printf("Error reading raw frame: %v, time: %d", err, time.Now().UnixMilli())
+			c.logger.Error("Error reading raw frame", "error", err)
 			return
 		}
 
-		logger.Printf("Message Type: %d", messageType)
+		c.logger.Debug("Received message", "message_type", messageType)
 		message, err := io.ReadAll(r)
 		if err != nil {
-			logger.Printf("Error reading message: %v", err)
-			logger.Printf("Raw Message: %s", string(message))
+			c.logger.Error("Error reading message", "error", err, "message", string(message))
 			return
 		}
 
 		// Reset read deadline after successful read
 		c.conn.SetReadDeadline(time.Now().Add(time.Second * 60))
+		c.metrics.eventsReceived.Add(1)
+		c.metrics.touch()
 
 		var data map[string]interface{}
 		if err := json.Unmarshal(message, &data); err != nil {
-			logger.Printf("Error unmarshalling message: %v, %s", err, string(message))
+			c.logger.Error("Error unmarshalling message", "error", err, "message", string(message))
 			continue
 		}
 
-		logger.Debugf("Received event: %s", prettyPrint(data))
-		logger.Infof("Received event type: %s", data["type"])
+		c.logger.Debug("Received event", "event", prettyPrint(data))
+		c.logger.Info("Received event", "type", data["type"])
+		// Events renamed by the GA protocol are handled under their beta names
+		eventType, _ := data["type"].(string)
+		eventType = c.protocol.normalizeEventType(eventType)
+		metrics.EventsReceived.WithLabelValues(metricsBackend, eventType).Inc()
 
-		switch data["type"] {
+		switch eventType {
 		case "response.audio.delta":
 			c.assistantIsTalking = true
-			go handleResponseAudioDelta(c, message)
+			c.spoke.Store(true)
+			handleResponseAudioDelta(c, message)
 		case "response.created":
 			c.assistantIsTalking = true
+			created := ResponseCreated{}
+			json.Unmarshal(message, &created)
+			c.metrics.responseStarted(created.Response.ID)
+			c.guardResponseStarted(created.Response.ID)
+			c.trace.responseCreated(created.Response.ID)
+			c.latency.responseCreated(created.Response.ID)
+			if eventID := created.Response.Metadata[responseEventIDKey]; eventID != "" {
+				c.resolvePendingResponse(eventID, responseResult{responseID: created.Response.ID})
+			}
+			c.emit(provider.Event{Type: provider.ResponseStarted, ResponseID: created.Response.ID})
 		case "response.audio.done":
 			c.assistantIsTalking = false
 		case "response.done":
 			c.assistantIsTalking = false
+			handleResponseDone(c, message)
+		case "input_audio_buffer.speech_started":
+			handleSpeech(c, message, provider.SpeechStarted)
+		case "input_audio_buffer.speech_stopped":
+			handleSpeech(c, message, provider.SpeechStopped)
+		case "input_audio_buffer.committed":
+			committed := InputAudioBufferCommitted{}
+			json.Unmarshal(message, &committed)
+			c.trace.committed(committed.ItemID)
+			c.latency.committed()
+		case "conversation.item.truncated":
+			truncated := ConversationItemTruncated{}
+			json.Unmarshal(message, &truncated)
+			c.trace.truncated(truncated.ItemID, truncated.AudioEndMS)
+		case "conversation.item.input_audio_transcription.delta":
+			handleInputAudioTranscriptionDelta(c, message)
+		case "conversation.item.input_audio_transcription.completed":
+			handleInputAudioTranscriptionCompleted(c, message)
+		case "conversation.item.input_audio_transcription.failed":
+			handleInputAudioTranscriptionFailed(c, message)
+		case "response.audio_transcript.delta", "response.text.delta":
+			handleResponseTranscriptDelta(c, message)
+		case "response.audio_transcript.done", "response.text.done":
+			handleResponseTranscriptDone(c, message)
+		case "response.function_call_arguments.done":
+			handleFunctionCallArgumentsDone(c, message)
 		case "conversation.item.created":
 			c.assistantIsTalking = true
+		case "error":
+			c.metrics.errors.Add(1)
+			c.logger.Error("Received error event", "event", prettyPrint(data))
+			errorEvent := ErrorEvent{}
+			json.Unmarshal(message, &errorEvent)
+			code := errorEvent.Error.Code
+			if code == "" {
+				code = errorEvent.Error.Type
+			}
+			metrics.Errors.WithLabelValues(metricsBackend, code).Inc()
+			if errorEvent.Error.EventID != "" {
+				c.resolvePendingResponse(errorEvent.Error.EventID, responseResult{
+					err: fmt.Errorf("%s: %s", errorEvent.Error.Code, errorEvent.Error.Message),
+				})
+			}
+			c.emit(provider.Event{Type: provider.Error, Text: errorEvent.Error.Message})
 		default:
-			logger.Printf("Unhandled event type: %s", data["type"])
+			if !c.emitRaw(eventType, message) {
+				c.logger.Warn("Unhandled event", "type", eventType)
+			}
 		}
 	}
 }
 
 func (c *OpenAIRealtimeClient) listenForAudioInput() {
-	for audio := range c.audioInput {
-		if !c.assistantIsTalking {
-			c.sendEvent(InputAudioBufferAppend{
-				EventID: uuid.NewString(),
-				Type:    "input_audio_buffer.append",
-				Audio:   base64.StdEncoding.EncodeToString(audio),
-			})
+	for {
+		select {
+		case audio, ok := <-c.audioInput:
+			if !ok {
+				return
+			}
+			c.appendInputAudio(audio)
+		case <-c.done:
+			return
+		case result := <-c.turnEnds:
+			// Append audio captured before the turn ended so the commit includes it
+			for len(c.audioInput) > 0 {
+				c.appendInputAudio(<-c.audioInput)
+			}
+			result <- c.commitTurn()
 		}
 	}
 }
 
+func (c *OpenAIRealtimeClient) appendInputAudio(audio []byte) {
+	if !c.shouldForwardAudio(audio) {
+		return
+	}
+	if err := c.sendEvent(InputAudioBufferAppend{
+		EventID: uuid.NewString(),
+		Type:    "input_audio_buffer.append",
+		Audio:   base64.StdEncoding.EncodeToString(audio),
+	}); err == nil {
+		c.metrics.audioBytesIn.Add(int64(len(audio)))
+		metrics.AudioBytes.WithLabelValues(metricsBackend, "in").Add(float64(len(audio)))
+	}
+}
+
+// StartTurn starts a new user turn by clearing any uncommitted input audio
+func (c *OpenAIRealtimeClient) StartTurn() error {
+	c.trace.speechStarted("")
+	return c.sendEvent(InputAudioBufferClear{
+		EventID: uuid.NewString(),
+		Type:    "input_audio_buffer.clear",
+	})
+}
+
+// EndTurn commits the input audio and asks the assistant to respond. It is
+// used with ManualTurnDetection, where the server does not detect turns itself.
+func (c *OpenAIRealtimeClient) EndTurn() error {
+	c.latency.speechEnded()
+	result := make(chan error, 1)
+	select {
+	case c.turnEnds <- result:
+		return <-result
+	case <-c.done:
+		return errors.New("client is closed")
+	}
+}
+
+func (c *OpenAIRealtimeClient) commitTurn() error {
+	c.trace.speechStopped("")
+	if err := c.sendEvent(InputAudioBufferCommit{
+		EventID: uuid.NewString(),
+		Type:    "input_audio_buffer.commit",
+	}); err != nil {
+		return fmt.Errorf("error committing input audio: %w", err)
+	}
+	c.metrics.turnEnded()
+	c.latency.committed()
+	if err := c.sendEvent(ResponseCreate{
+		EventID: uuid.NewString(),
+		Type:    "response.create",
+	}); err != nil {
+		return fmt.Errorf("error creating response: %w", err)
+	}
+	return nil
+}
+
+type responseResult struct {
+	responseID string
+	err        error
+}
+
+// CreateResponse asks the assistant to respond to the conversation so far,
+// overriding the session defaults with opts. It waits for the response to be
+// created and returns its ID, which appears on the events of the response.
+func (c *OpenAIRealtimeClient) CreateResponse(opts ResponseOptions) (string, error) {
+	eventID := uuid.NewString()
+
+	// Tag the response so its response.created event can be matched to this call
+	metadata := make(map[string]string, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[responseEventIDKey] = eventID
+	opts.Metadata = metadata
+
+	result := make(chan responseResult, 1)
+	c.pendingMu.Lock()
+	c.pendingResponses[eventID] = result
+	c.pendingMu.Unlock()
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pendingResponses, eventID)
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.sendEvent(ResponseCreate{
+		EventID:  eventID,
+		Type:     "response.create",
+		Response: &opts,
+	}); err != nil {
+		return "", fmt.Errorf("error creating response: %w", err)
+	}
+
+	timer := time.NewTimer(responseCreatedTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-result:
+		return r.responseID, r.err
+	case <-timer.C:
+		return "", errors.New("timed out waiting for response to be created")
+	case <-c.done:
+		return "", errors.New("client is closed")
+	}
+}
+
+// resolvePendingResponse delivers the result of a CreateResponse call
+func (c *OpenAIRealtimeClient) resolvePendingResponse(eventID string, result responseResult) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if pending, ok := c.pendingResponses[eventID]; ok {
+		pending <- result
+		delete(c.pendingResponses, eventID)
+	}
+}
+
+// shouldForwardAudio applies the duplex policy to a chunk of microphone audio
+func (c *OpenAIRealtimeClient) shouldForwardAudio(audio []byte) bool {
+	if !c.assistantIsTalking {
+		return true
+	}
+
+	switch c.duplexMode {
+	case FullDuplex:
+		return true
+	case EchoGated:
+		now := time.Now()
+		samples, err := audioformat.Decode(c.session.InputAudioFormat, audio)
+		if err == nil && audioformat.RMS(samples) > c.playback.Level()*c.echoGateRatio {
+			c.echoGateOpenUntil = now.Add(echoGateHold)
+		}
+		return now.Before(c.echoGateOpenUntil)
+	default:
+		return false
+	}
+}
+
 func (c *OpenAIRealtimeClient) sendEvent(event interface{}) error {
-	payload, err := json.Marshal(event)
+	if c.conn == nil {
+		return errors.New("client is not connected")
+	}
+
+	payload, err := json.Marshal(c.protocol.encode(event))
 	if err != nil {
 		return fmt.Errorf("error marshalling event: %w", err)
 	}
-	return c.conn.WriteMessage(websocket.TextMessage, payload)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+		c.metrics.errors.Add(1)
+		return err
+	}
+	c.metrics.eventsSent.Add(1)
+	metrics.EventsSent.WithLabelValues(metricsBackend, eventType(event)).Inc()
+	c.metrics.touch()
+	return nil
 }
 
-func sendInitialSessionConfig(conn *websocket.Conn) error {
-	sessionUpdate := SessionUpdate{
-		EventID: uuid.NewString(),
-		Type:    "session.update",
+// newSession builds the initial session config
+func newSession(config Config) (Session, error) {
+	session := Session{
+		Modalities:              []string{"text", "audio"},
+		Instructions:            initialPrompt,
+		Voice:                   "ash",
+		InputAudioFormat:        audioformat.PCM16,
+		OutputAudioFormat:       audioformat.PCM16,
+		ToolChoice:              "auto",
+		Temperature:             0.8,
+		MaxResponseOutputTokens: "inf",
+	}
+	session.InputAudioTranscription = newInputAudioTranscription(config.Transcription)
+
+	noiseReduction, err := newNoiseReduction(config.NoiseReduction)
+	if err != nil {
+		return Session{}, err
+	}
+	session.InputAudioNoiseReduction = noiseReduction
+
+	if config.Instructions != "" {
+		session.Instructions = config.Instructions
+	}
+
+	if config.InputAudioFormat != "" {
+		if err := config.InputAudioFormat.Validate(); err != nil {
+			return Session{}, err
+		}
+		session.InputAudioFormat = config.InputAudioFormat
+	}
+	if config.OutputAudioFormat != "" {
+		if err := config.OutputAudioFormat.Validate(); err != nil {
+			return Session{}, err
+		}
+		session.OutputAudioFormat = config.OutputAudioFormat
+	}
+
+	turnDetection, err := newTurnDetection(config.TurnDetection, config.VAD)
+	if err != nil {
+		return Session{}, err
+	}
+	session.TurnDetection = turnDetection
+
+	return session, nil
+}
+
+// newInputAudioTranscription builds the transcription config, nil when disabled
+func newInputAudioTranscription(opts TranscriptionOptions) *InputAudioTranscription {
+	if opts.Disabled {
+		return nil
 	}
-	sessionUpdate.Session.Modalities = []string{"text", "audio"}
-	sessionUpdate.Session.Instructions = initialPrompt
-	sessionUpdate.Session.Voice = "ash"
-	sessionUpdate.Session.InputAudioFormat = "pcm16"
-	sessionUpdate.Session.OutputAudioFormat = "pcm16"
-	sessionUpdate.Session.InputAudioTranscription.Model = "whisper-1"
-	sessionUpdate.Session.TurnDetection = struct {
-		Type              string  `json:"type"`
-		Threshold         float64 `json:"threshold"`
-		PrefixPaddingMS   int     `json:"prefix_padding_ms"`
-		SilenceDurationMS int     `json:"silence_duration_ms"`
-		CreateResponse    bool    `json:"create_response"`
-	}{
-		Type:              "server_vad",
-		Threshold:         0.75,
-		PrefixPaddingMS:   300,
-		SilenceDurationMS: 500,
+	transcription := &InputAudioTranscription{
+		Model:    defaultTranscriptionModel,
+		Language: opts.Language,
+		Prompt:   opts.Prompt,
+	}
+	if opts.Model != "" {
+		transcription.Model = opts.Model
+	}
+	return transcription
+}
+
+// newNoiseReduction builds the noise reduction config for a mode, nil for NoNoiseReduction
+func newNoiseReduction(mode NoiseReductionMode) (*NoiseReduction, error) {
+	switch mode {
+	case NoNoiseReduction:
+		return nil, nil
+	case NearField, FarField:
+		return &NoiseReduction{Type: mode}, nil
+	default:
+		return nil, fmt.Errorf("unknown noise reduction mode: %q", mode)
+	}
+}
+
+// newTurnDetection builds the turn detection config for a mode, nil for ManualTurnDetection
+func newTurnDetection(mode TurnDetectionMode, opts VADOptions) (*TurnDetection, error) {
+	turnDetection := &TurnDetection{
 		CreateResponse:    true,
+		InterruptResponse: !opts.DisableInterruptResponse,
+	}
+
+	switch mode {
+	case "", ServerVAD:
+		turnDetection.Type = string(ServerVAD)
+		turnDetection.Threshold = 0.75
+		turnDetection.PrefixPaddingMS = 300
+		turnDetection.SilenceDurationMS = 500
+		turnDetection.IdleTimeoutMS = opts.IdleTimeoutMS
+		if opts.Threshold > 0 {
+			turnDetection.Threshold = opts.Threshold
+		}
+		if opts.PrefixPaddingMS > 0 {
+			turnDetection.PrefixPaddingMS = opts.PrefixPaddingMS
+		}
+		if opts.SilenceDurationMS > 0 {
+			turnDetection.SilenceDurationMS = opts.SilenceDurationMS
+		}
+	case SemanticVAD:
+		turnDetection.Type = string(SemanticVAD)
+		switch opts.Eagerness {
+		case "":
+			turnDetection.Eagerness = EagernessAuto
+		case EagernessLow, EagernessMedium, EagernessHigh, EagernessAuto:
+			turnDetection.Eagerness = opts.Eagerness
+		default:
+			return nil, fmt.Errorf("unknown eagerness: %q", opts.Eagerness)
+		}
+	case ManualTurnDetection:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown turn detection mode: %q", mode)
+	}
+
+	return turnDetection, nil
+}
+
+// SetInstructions changes the assistant instructions of the running session.
+// With agents, they are the instructions of agents without their own.
+func (c *OpenAIRealtimeClient) SetInstructions(instructions string) error {
+	c.sessionMu.Lock()
+	c.baseInstructions = instructions
+	if agent, ok := c.agents[c.agent]; !ok || agent.Instructions == "" {
+		c.session.Instructions = instructions
 	}
-	sessionUpdate.Session.ToolChoice = "auto"
-	sessionUpdate.Session.Temperature = 0.8
-	sessionUpdate.Session.MaxResponseOutputTokens = "inf"
+	c.sessionMu.Unlock()
 
-	payload, err := json.Marshal(sessionUpdate)
+	return c.sendSessionUpdate()
+}
+
+// SetTurnDetection changes the turn detection mode and tuning of the running session
+func (c *OpenAIRealtimeClient) SetTurnDetection(mode TurnDetectionMode, opts VADOptions) error {
+	turnDetection, err := newTurnDetection(mode, opts)
 	if err != nil {
-		return fmt.Errorf("error marshalling session update: %w", err)
+		return err
 	}
 
-	if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
-		logger.Errorf("Error sending session update: %v", err)
+	c.sessionMu.Lock()
+	c.session.TurnDetection = turnDetection
+	c.sessionMu.Unlock()
+
+	return c.sendSessionUpdate()
+}
+
+// SetTranscription changes the transcription of the user's speech in the running session
+func (c *OpenAIRealtimeClient) SetTranscription(opts TranscriptionOptions) error {
+	c.sessionMu.Lock()
+	c.session.InputAudioTranscription = newInputAudioTranscription(opts)
+	c.sessionMu.Unlock()
+
+	return c.sendSessionUpdate()
+}
+
+// SetNoiseReduction changes the noise reduction of the running session, e.g.
+// when the user switches between a headset and a laptop microphone
+func (c *OpenAIRealtimeClient) SetNoiseReduction(mode NoiseReductionMode) error {
+	noiseReduction, err := newNoiseReduction(mode)
+	if err != nil {
+		return err
+	}
+
+	c.sessionMu.Lock()
+	c.session.InputAudioNoiseReduction = noiseReduction
+	c.sessionMu.Unlock()
+
+	return c.sendSessionUpdate()
+}
+
+func (c *OpenAIRealtimeClient) sendSessionUpdate() error {
+	c.sessionMu.Lock()
+	session := c.session
+	session.Tools = c.availableTools(session.Tools)
+	c.sessionMu.Unlock()
+
+	if err := c.sendEvent(SessionUpdate{
+		EventID: uuid.NewString(),
+		Type:    "session.update",
+		Session: session,
+	}); err != nil {
+		c.logger.Error("Error sending session update", "error", err)
 		return fmt.Errorf("error sending session update: %w", err)
 	}
 
-	logger.Printf("Connected to server. Sent initial session config.")
+	c.logger.Info("Sent session config")
 	return nil
 }
 
@@ -259,7 +827,7 @@ func (c *OpenAIRealtimeClient) startPinger() {
 
 	for range ticker.C {
 		if err := c.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(10*time.Second)); err != nil {
-			logger.Printf("Failed to send ping: %v", err)
+			c.logger.Error("Failed to send ping", "error", err)
 			return
 		}
 	}
@@ -0,0 +1,169 @@
+package openairealtime
+
+import (
+	"realtime/pkg/audioformat"
+)
+
+// gaEventTypes maps the server events renamed by the GA protocol to their
+// beta names, which the client handles
+var gaEventTypes = map[string]string{
+	"response.output_audio.delta":            "response.audio.delta",
+	"response.output_audio.done":             "response.audio.done",
+	"response.output_audio_transcript.delta": "response.audio_transcript.delta",
+	"response.output_audio_transcript.done":  "response.audio_transcript.done",
+	"response.output_text.delta":             "response.text.delta",
+	"response.output_text.done":              "response.text.done",
+	"conversation.item.added":                "conversation.item.created",
+}
+
+// normalizeEventType returns the beta name of a server event, so events of
+// both protocols are handled the same way
+func (p Protocol) normalizeEventType(eventType string) string {
+	if p == ProtocolGA {
+		if beta, ok := gaEventTypes[eventType]; ok {
+			return beta
+		}
+	}
+	return eventType
+}
+
+// encode translates a client event to the protocol. Events that are the same
+// in both protocols are returned as they are.
+func (p Protocol) encode(event interface{}) interface{} {
+	if p != ProtocolGA {
+		return event
+	}
+	switch e := event.(type) {
+	case SessionUpdate:
+		return gaSessionUpdate{EventID: e.EventID, Type: e.Type, Session: newGASession(e.Session)}
+	case ResponseCreate:
+		if e.Response == nil {
+			return e
+		}
+		return gaResponseCreate{EventID: e.EventID, Type: e.Type, Response: newGAResponseOptions(*e.Response)}
+	default:
+		return event
+	}
+}
+
+// session.update of the GA protocol
+type gaSessionUpdate struct {
+	EventID string    `json:"event_id"`
+	Type    string    `json:"type"`
+	Session gaSession `json:"session"`
+}
+
+// gaSession is the session configuration of the GA protocol. It has no
+// temperature, so Session.Temperature is ignored.
+type gaSession struct {
+	Type             string         `json:"type"`              // Always realtime
+	OutputModalities []string       `json:"output_modalities"` // Either audio, which includes its transcript, or text
+	Instructions     string         `json:"instructions"`
+	Audio            gaSessionAudio `json:"audio"`
+	Tools            []SessionTool  `json:"tools"`
+	ToolChoice       string         `json:"tool_choice"`
+	MaxOutputTokens  string         `json:"max_output_tokens"`
+}
+
+// gaSessionAudio holds the audio settings of a GA session
+type gaSessionAudio struct {
+	Input struct {
+		Format         gaAudioFormat            `json:"format"`
+		Transcription  *InputAudioTranscription `json:"transcription"`   // nil disables transcription
+		NoiseReduction *NoiseReduction          `json:"noise_reduction"` // nil disables noise reduction
+		TurnDetection  *TurnDetection           `json:"turn_detection"`  // nil disables turn detection
+	} `json:"input"`
+	Output struct {
+		Format gaAudioFormat `json:"format"`
+		Voice  string        `json:"voice"`
+	} `json:"output"`
+}
+
+// gaAudioFormat is an audio format of the GA protocol
+type gaAudioFormat struct {
+	Type string `json:"type"`           // audio/pcm, audio/pcmu or audio/pcma
+	Rate int    `json:"rate,omitempty"` // audio/pcm only
+}
+
+// response.create of the GA protocol
+type gaResponseCreate struct {
+	EventID  string             `json:"event_id"`
+	Type     string             `json:"type"`
+	Response *gaResponseOptions `json:"response,omitempty"`
+}
+
+// gaResponseOptions overrides the session defaults for a single response. It
+// has no temperature, so ResponseOptions.Temperature is ignored.
+type gaResponseOptions struct {
+	Instructions     string            `json:"instructions,omitempty"`
+	OutputModalities []string          `json:"output_modalities,omitempty"`
+	Audio            *gaResponseAudio  `json:"audio,omitempty"`
+	ToolChoice       *ToolChoice       `json:"tool_choice,omitempty"`
+	MaxOutputTokens  int               `json:"max_output_tokens,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// gaResponseAudio holds the audio settings of a GA response
+type gaResponseAudio struct {
+	Output struct {
+		Voice string `json:"voice"`
+	} `json:"output"`
+}
+
+func newGASession(session Session) gaSession {
+	ga := gaSession{
+		Type:             "realtime",
+		OutputModalities: gaModalities(session.Modalities),
+		Instructions:     session.Instructions,
+		Tools:            session.Tools,
+		ToolChoice:       session.ToolChoice,
+		MaxOutputTokens:  session.MaxResponseOutputTokens,
+	}
+	ga.Audio.Input.Format = newGAAudioFormat(session.InputAudioFormat)
+	ga.Audio.Input.Transcription = session.InputAudioTranscription
+	ga.Audio.Input.NoiseReduction = session.InputAudioNoiseReduction
+	ga.Audio.Input.TurnDetection = session.TurnDetection
+	ga.Audio.Output.Format = newGAAudioFormat(session.OutputAudioFormat)
+	ga.Audio.Output.Voice = session.Voice
+	return ga
+}
+
+func newGAResponseOptions(opts ResponseOptions) *gaResponseOptions {
+	ga := &gaResponseOptions{
+		Instructions:     opts.Instructions,
+		OutputModalities: gaModalities(opts.Modalities),
+		ToolChoice:       opts.ToolChoice,
+		MaxOutputTokens:  opts.MaxOutputTokens,
+		Metadata:         opts.Metadata,
+	}
+	if opts.Voice != "" {
+		ga.Audio = &gaResponseAudio{}
+		ga.Audio.Output.Voice = opts.Voice
+	}
+	return ga
+}
+
+func newGAAudioFormat(format audioformat.Format) gaAudioFormat {
+	switch format {
+	case audioformat.G711ULaw:
+		return gaAudioFormat{Type: "audio/pcmu"}
+	case audioformat.G711ALaw:
+		return gaAudioFormat{Type: "audio/pcma"}
+	default:
+		return gaAudioFormat{Type: "audio/pcm", Rate: audioformat.PCM16.SampleRate()}
+	}
+}
+
+// gaModalities converts beta modalities, e.g. text and audio, to the single
+// output modality of the GA protocol
+func gaModalities(modalities []string) []string {
+	if len(modalities) == 0 {
+		return nil
+	}
+	for _, modality := range modalities {
+		if modality == "audio" {
+			return []string{"audio"}
+		}
+	}
+	return []string{"text"}
+}
@@ -0,0 +1,205 @@
+package openairealtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"realtime/pkg/metrics"
+	"realtime/pkg/provider"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// emptyParameters is the JSON schema used for tools without arguments
+var emptyParameters = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// RegisterTool declares a tool the assistant can call. Tools registered
+// after Start are sent to the running session.
+func (c *OpenAIRealtimeClient) RegisterTool(tool provider.Tool) error {
+	if tool.Name == "" {
+		return errors.New("tool name is required")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+
+	parameters := tool.Parameters
+	if len(parameters) == 0 {
+		parameters = emptyParameters
+	}
+
+	c.sessionMu.Lock()
+	if _, ok := c.tools[tool.Name]; ok {
+		c.sessionMu.Unlock()
+		return fmt.Errorf("tool %s is already registered", tool.Name)
+	}
+	c.tools[tool.Name] = tool
+	c.session.Tools = append(c.session.Tools, SessionTool{
+		Type:        "function",
+		Name:        tool.Name,
+		Description: tool.Description,
+		Parameters:  parameters,
+	})
+	_, canCancel := c.tools[cancelTaskToolName]
+	c.sessionMu.Unlock()
+
+	// The assistant cancels async tools it started when the user changes their mind
+	if tool.Async && !canCancel {
+		return c.RegisterTool(c.cancelTaskTool())
+	}
+
+	if c.started.Load() {
+		return c.sendSessionUpdate()
+	}
+	return nil
+}
+
+// handleFunctionCallArgumentsDone records a tool call, which is run once its response is done
+func handleFunctionCallArgumentsDone(client *OpenAIRealtimeClient, b []byte) {
+	event := ResponseFunctionCallArgumentsDone{}
+	json.Unmarshal(b, &event)
+
+	call := provider.ToolCall{
+		CallID:    event.CallID,
+		Name:      event.Name,
+		Arguments: json.RawMessage(event.Arguments),
+	}
+
+	client.toolCallsMu.Lock()
+	client.toolCalls[event.ResponseID] = append(client.toolCalls[event.ResponseID], call)
+	client.toolCallsMu.Unlock()
+
+	client.emit(provider.Event{
+		Type:       provider.ToolCalled,
+		ResponseID: event.ResponseID,
+		ItemID:     event.ItemID,
+		ToolCall:   &call,
+	})
+}
+
+// runToolCalls runs the tool calls of a finished response, reports their
+// outputs and asks the assistant to respond to them
+func (c *OpenAIRealtimeClient) runToolCalls(responseID string) {
+	c.toolCallsMu.Lock()
+	calls := c.toolCalls[responseID]
+	delete(c.toolCalls, responseID)
+	c.toolCallsMu.Unlock()
+
+	if len(calls) == 0 {
+		return
+	}
+	if c.guardBlocked(responseID) {
+		c.logger.Info("Dropped tool calls of a response cut off by a guardrail", "response_id", responseID)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, call := range calls {
+		wg.Add(1)
+		go func(call provider.ToolCall) {
+			defer wg.Done()
+			output := c.callTool(responseID, call)
+			if err := c.sendEvent(ConversationItemCreate{
+				EventID: uuid.NewString(),
+				Type:    "conversation.item.create",
+				Item: ConversationItem{
+					Type:   "function_call_output",
+					CallID: call.CallID,
+					Output: output,
+				},
+			}); err != nil {
+				c.logger.Error("Error sending tool output", "tool", call.Name, "call_id", call.CallID, "error", err)
+			}
+		}(call)
+	}
+	wg.Wait()
+
+	if err := c.sendEvent(ResponseCreate{
+		EventID: uuid.NewString(),
+		Type:    "response.create",
+	}); err != nil {
+		c.logger.Error("Error creating response to tool outputs", "response_id", responseID, "error", err)
+	}
+}
+
+// callTool runs a tool handler and returns the output for the assistant
+func (c *OpenAIRealtimeClient) callTool(responseID string, call provider.ToolCall) string {
+	span := c.trace.toolCall(responseID, call.Name, call.CallID)
+	defer span.End()
+
+	c.sessionMu.Lock()
+	tool, ok := c.tools[call.Name]
+	c.sessionMu.Unlock()
+	if !ok {
+		c.logger.Warn("Assistant called unknown tool", "tool", call.Name, "call_id", call.CallID)
+		return toolError(fmt.Errorf("unknown tool %s", call.Name))
+	}
+	if !c.canCall(call.Name) {
+		c.logger.Warn("Agent called a tool it does not have", "tool", call.Name, "call_id", call.CallID, "agent", c.Agent())
+		return toolError(fmt.Errorf("%s is not available to the %s agent", call.Name, c.Agent()))
+	}
+
+	if tool.RequiresConfirmation {
+		if err := c.approval.Authorize(c.ctx, call); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return toolError(err)
+		}
+	}
+
+	if tool.Async {
+		return c.startTask(tool, call)
+	}
+
+	output, err := c.runHandler(c.ctx, tool, call)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return output
+}
+
+// runHandler runs a tool handler and returns its output, or the error
+// formatted as an output
+func (c *OpenAIRealtimeClient) runHandler(ctx context.Context, tool provider.Tool, call provider.ToolCall) (string, error) {
+	c.logger.Info("Calling tool", "tool", call.Name, "call_id", call.CallID, "arguments", string(call.Arguments))
+	start := time.Now()
+	output, err := tool.Handler(ctx, call.Arguments)
+	metrics.ToolCalled(metricsBackend, call.Name, start, err)
+	if err != nil {
+		c.logger.Error("Tool failed", "tool", call.Name, "call_id", call.CallID, "error", err)
+		return c.modelPII.Redact(toolError(err)), err
+	}
+	return c.modelPII.Redact(output), nil
+}
+
+// toolError formats an error as a tool output the assistant can explain to the user
+func toolError(err error) string {
+	output, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(output)
+}
+
+// SendText sends a text message from the user and asks the assistant to respond
+func (c *OpenAIRealtimeClient) SendText(text string) error {
+	if err := c.sendEvent(ConversationItemCreate{
+		EventID: uuid.NewString(),
+		Type:    "conversation.item.create",
+		Item: ConversationItem{
+			Type:    "message",
+			Role:    "user",
+			Content: []ContentPart{{Type: "input_text", Text: c.modelPII.Redact(text)}},
+		},
+	}); err != nil {
+		return fmt.Errorf("error sending text: %w", err)
+	}
+	if err := c.sendEvent(ResponseCreate{
+		EventID: uuid.NewString(),
+		Type:    "response.create",
+	}); err != nil {
+		return fmt.Errorf("error creating response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package openairealtime
+
+import (
+	"context"
+	"realtime/pkg/audioformat"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("realtime/pkg/openairealtime")
+
+// turnTrace traces each conversation turn as one trace. The turn span covers
+// the user's speech and every response to it, including those following tool
+// calls, with child spans for each stage.
+type turnTrace struct {
+	mu        sync.Mutex
+	sessionID string
+	ctx       context.Context // Context of the current turn span
+	turn      trace.Span
+	speech    trace.Span
+	commit    trace.Span
+	responses map[string]*responseTrace
+	playEnd   time.Time // When the audio of the turn finishes playing, the turn ends no earlier
+}
+
+type responseTrace struct {
+	ctx        context.Context // Context of the response span
+	span       trace.Span
+	firstAudio trace.Span
+	playback   trace.Span
+	playStart  time.Time
+	audioBytes int
+}
+
+func newTurnTrace(sessionID string) *turnTrace {
+	return &turnTrace{sessionID: sessionID, responses: make(map[string]*responseTrace)}
+}
+
+// startTurn ends any current turn and starts a new one
+func (t *turnTrace) startTurn() {
+	t.endTurn()
+	t.ctx, t.turn = tracer.Start(context.Background(), "turn",
+		trace.WithAttributes(attribute.String("session.id", t.sessionID)))
+}
+
+// endTurn ends the current turn and any spans still open within it
+func (t *turnTrace) endTurn() {
+	if t.turn == nil {
+		return
+	}
+	endSpan(&t.speech)
+	endSpan(&t.commit)
+	for id, response := range t.responses {
+		endSpan(&response.firstAudio)
+		endSpan(&response.playback)
+		endSpan(&response.span)
+		delete(t.responses, id)
+	}
+	end := time.Now()
+	if t.playEnd.After(end) {
+		end = t.playEnd
+	}
+	t.turn.End(trace.WithTimestamp(end))
+	t.turn = nil
+	t.playEnd = time.Time{}
+}
+
+// ensureTurn starts a turn for responses without user speech, e.g. to text
+func (t *turnTrace) ensureTurn() {
+	if t.turn == nil {
+		t.startTurn()
+	}
+}
+
+// speechStarted starts a turn with the user's speech
+func (t *turnTrace) speechStarted(itemID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.startTurn()
+	_, t.speech = tracer.Start(t.ctx, "user_speech", trace.WithAttributes(attribute.String("item.id", itemID)))
+}
+
+// speechStopped ends the user's speech and waits for the audio to be committed
+func (t *turnTrace) speechStopped(itemID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ensureTurn()
+	if t.speech != nil && itemID != "" {
+		t.speech.SetAttributes(attribute.String("item.id", itemID))
+	}
+	endSpan(&t.speech)
+	if t.commit == nil {
+		_, t.commit = tracer.Start(t.ctx, "commit")
+	}
+}
+
+// committed ends the commit span once the server has committed the audio
+func (t *turnTrace) committed(itemID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.commit != nil {
+		t.commit.SetAttributes(attribute.String("item.id", itemID))
+	}
+	endSpan(&t.commit)
+}
+
+// responseCreated starts the response span and waits for its first audio
+func (t *turnTrace) responseCreated(responseID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ensureTurn()
+	endSpan(&t.commit)
+
+	response := &responseTrace{}
+	response.ctx, response.span = tracer.Start(t.ctx, "response",
+		trace.WithAttributes(attribute.String("response.id", responseID)))
+	_, response.firstAudio = tracer.Start(response.ctx, "first_audio_delta")
+	t.responses[responseID] = response
+}
+
+// responseAudio records audio of a response, starting its playback on the first delta
+func (t *turnTrace) responseAudio(responseID, itemID string, bytes int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	response, ok := t.responses[responseID]
+	if !ok {
+		return
+	}
+	if response.firstAudio != nil {
+		response.firstAudio.SetAttributes(attribute.String("item.id", itemID))
+		endSpan(&response.firstAudio)
+		response.playStart = time.Now()
+		_, response.playback = tracer.Start(response.ctx, "playback",
+			trace.WithTimestamp(response.playStart),
+			trace.WithAttributes(attribute.String("item.id", itemID)))
+	}
+	response.audioBytes += bytes
+}
+
+// responseDone ends the response. Playback is assumed to end once all of the
+// response's audio has played, and the turn ends unless tools were called.
+func (t *turnTrace) responseDone(responseID, status string, format audioformat.Format, toolCalls bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	response, ok := t.responses[responseID]
+	if !ok {
+		return
+	}
+	delete(t.responses, responseID)
+
+	endSpan(&response.firstAudio)
+	if response.playback != nil {
+		playEnd := response.playStart.Add(format.Duration(response.audioBytes))
+		if status == "cancelled" || playEnd.Before(time.Now()) {
+			playEnd = time.Now()
+		}
+		response.playback.End(trace.WithTimestamp(playEnd))
+		if playEnd.After(t.playEnd) {
+			t.playEnd = playEnd
+		}
+	}
+	if status == "cancelled" {
+		_, cancelled := tracer.Start(response.ctx, "cancellation")
+		cancelled.End()
+	}
+	response.span.SetAttributes(attribute.String("response.status", status))
+	response.span.End()
+
+	if !toolCalls && len(t.responses) == 0 {
+		t.endTurn()
+	}
+}
+
+// truncated records the truncation of an assistant item that was interrupted
+func (t *turnTrace) truncated(itemID string, audioEndMS int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.turn == nil {
+		return
+	}
+	_, span := tracer.Start(t.ctx, "truncation", trace.WithAttributes(
+		attribute.String("item.id", itemID),
+		attribute.Int("audio_end_ms", audioEndMS),
+	))
+	span.End()
+}
+
+// toolCall starts a span for a tool call within its response, end it when the call returns
+func (t *turnTrace) toolCall(responseID, name, callID string) trace.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ctx := t.ctx
+	if response, ok := t.responses[responseID]; ok {
+		ctx = response.ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := tracer.Start(ctx, "tool_call", trace.WithAttributes(
+		attribute.String("tool.name", name),
+		attribute.String("tool.call_id", callID),
+		attribute.String("response.id", responseID),
+	))
+	return span
+}
+
+// close ends the current turn
+func (t *turnTrace) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endTurn()
+}
+
+func endSpan(span *trace.Span) {
+	if *span != nil {
+		(*span).End()
+		*span = nil
+	}
+}
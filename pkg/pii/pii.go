@@ -0,0 +1,292 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detector finds one kind of personal data in text
+type Detector struct {
+	Name    string                  // Replaces matches as [NAME], e.g. [EMAIL]
+	Pattern *regexp.Regexp          // Only its group named value is replaced if it has one
+	Valid   func(match string) bool // Optional check of matches, e.g. a checksum
+	Token   bool                    // Matches must be whole tokens, not part of a longer one such as a UUID or an IP address
+}
+
+// Detectors are the built-in detectors by name
+var Detectors = map[string]Detector{
+	"email": {
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	"card": {
+		Name: "card",
+		// Ungrouped, in groups of four or grouped 4-6-5 like Amex
+		Pattern: regexp.MustCompile(`\d{13,19}|\d{4}[ \-]\d{4}[ \-]\d{4}[ \-]\d{1,4}(?:[ \-]\d{3})?|\d{4}[ \-]\d{6}[ \-]\d{4,5}`),
+		Valid:   luhn,
+		Token:   true,
+	},
+	"ssn": {
+		Name:    "ssn",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	},
+	"iban": {
+		Name:    "iban",
+		Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`),
+	},
+	"phone": {
+		Name: "phone",
+		// With a country code, or an area code in parentheses followed by two
+		// groups, or three groups ending in four digits, e.g. 415 555 0100
+		Pattern: regexp.MustCompile(`\+\d{1,3}[ .\-]?(?:\(\d{1,4}\)[ .\-]?)?\d{1,4}(?:[ .\-]\d{2,4}){1,3}` +
+			`|\(\d{2,4}\)[ .\-]?\d{3,4}[ .\-]\d{4}` +
+			`|\d{2,3}[ .\-]\d{3,4}[ .\-]\d{4}`),
+		Valid: validPhone,
+		Token: true,
+	},
+	"address": {
+		Name: "address",
+		Pattern: regexp.MustCompile(`(?i)\b\d{1,5}(?: [A-Z][a-z]+){1,4} (?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|terrace|square|sq)\b\.?` +
+			`(?:,? (?:apt|apartment|suite|unit) ?#?\w+)?`),
+	},
+}
+
+var (
+	// dottedQuad matches IPv4 addresses
+	dottedQuad = regexp.MustCompile(`^\d{1,3}(?:\.\d{1,3}){3}$`)
+	// date matches dates, e.g. 2026-10-18 in timestamps or 18.10.2026
+	date = regexp.MustCompile(`^(?:\d{4}[.\-/]\d{1,2}[.\-/]\d{1,2}|\d{1,2}[.\-/]\d{1,2}[.\-/]\d{2,4})`)
+)
+
+// validPhone tells phone numbers from other numbers with separators, such as
+// IP addresses and dates
+func validPhone(match string) bool {
+	n := digits(match)
+	return n >= 7 && n <= 15 && !dottedQuad.MatchString(match) && !date.MatchString(match)
+}
+
+// order runs detectors whose matches contain others' first, e.g. cards before phones
+var order = []string{"email", "iban", "card", "ssn", "address", "phone"}
+
+// Config selects the detectors of a Redactor
+type Config struct {
+	Detectors []string          // Built-in detectors to use, all of them if nil
+	Custom    map[string]string // Extra detectors, by name, as regular expressions, e.g. {"account": `ACC-\d{8}`}, only a group named value is replaced if there is one
+}
+
+// Redactor replaces personal data in text with placeholders naming its kind
+type Redactor struct {
+	detectors []Detector
+}
+
+// New returns a redactor with the configured detectors
+func New(config Config) (*Redactor, error) {
+	names := config.Detectors
+	if names == nil {
+		names = order
+	}
+	r := &Redactor{}
+	for _, name := range sortedByOrder(names) {
+		detector, ok := Detectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown PII detector %q", name)
+		}
+		r.detectors = append(r.detectors, detector)
+	}
+
+	custom := make([]string, 0, len(config.Custom))
+	for name := range config.Custom {
+		custom = append(custom, name)
+	}
+	sort.Strings(custom)
+	for _, name := range custom {
+		pattern, err := regexp.Compile(config.Custom[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of PII detector %s: %w", name, err)
+		}
+		// Custom patterns are the most specific, so they run first
+		r.detectors = append([]Detector{{Name: name, Pattern: pattern}}, r.detectors...)
+	}
+	return r, nil
+}
+
+// Redact replaces personal data in s, e.g. "mail jo@example.com" becomes
+// "mail [EMAIL]". A nil Redactor returns s unchanged.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, detector := range r.detectors {
+		s = detector.replace(s, "["+strings.ToUpper(detector.Name)+"]")
+	}
+	return s
+}
+
+// replace replaces the valid matches in s with placeholder. Only the value
+// group is replaced if the pattern has one, so the pattern can match the
+// text around it.
+func (d Detector) replace(s, placeholder string) string {
+	if d.Token {
+		return d.replaceTokens(s, placeholder)
+	}
+	group := d.Pattern.SubexpIndex("value")
+	var redacted strings.Builder
+	last := 0
+	for _, match := range d.Pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := match[0], match[1]
+		if group >= 0 && match[2*group] >= 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		if d.Valid != nil && !d.Valid(s[start:end]) {
+			continue
+		}
+		redacted.WriteString(s[last:start])
+		redacted.WriteString(placeholder)
+		last = end
+	}
+	if redacted.Len() == 0 {
+		return s
+	}
+	redacted.WriteString(s[last:])
+	return redacted.String()
+}
+
+// replaceTokens is replace for detectors of whole tokens. The characters
+// around each match are checked here rather than by the pattern, so the
+// separator between two numbers of a list is not taken by the first of them.
+func (d Detector) replaceTokens(s, placeholder string) string {
+	var redacted strings.Builder
+	last := 0
+	for pos := 0; pos < len(s); {
+		loc := d.Pattern.FindStringIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		end := d.cut(s, start, pos+loc[1])
+		if !d.matches(s[start:end]) || !isToken(s, start, end) {
+			pos = start + 1
+			continue
+		}
+		redacted.WriteString(s[last:start])
+		redacted.WriteString(placeholder)
+		last, pos = end, end
+	}
+	if redacted.Len() == 0 {
+		return s
+	}
+	redacted.WriteString(s[last:])
+	return redacted.String()
+}
+
+// cut ends the match s[start:end] before a group that begins another match,
+// e.g. the next of two phone numbers separated by a space, so the first does
+// not run into the second
+func (d Detector) cut(s string, start, end int) int {
+	for c := start + 1; c < end; c++ {
+		if !isSeparator(s[c-1]) || isSeparator(s[c]) {
+			continue
+		}
+		loc := d.Pattern.FindStringIndex(s[c:])
+		if loc == nil || loc[0] != 0 || c+loc[1] <= end || !d.matches(s[c:c+loc[1]]) {
+			continue
+		}
+		prefixEnd := c - 1
+		for prefixEnd > start && isSeparator(s[prefixEnd-1]) {
+			prefixEnd--
+		}
+		if d.matches(s[start:prefixEnd]) {
+			return prefixEnd
+		}
+	}
+	return end
+}
+
+// matches reports whether all of s is a valid match
+func (d Detector) matches(s string) bool {
+	loc := d.Pattern.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s) && (d.Valid == nil || d.Valid(s))
+}
+
+// isToken reports whether s[start:end] is a whole token, not part of a longer
+// one such as a UUID, a version or an IP address. A trailing period or dash
+// ends a token, e.g. at the end of a sentence.
+func isToken(s string, start, end int) bool {
+	if start > 0 {
+		if c := s[start-1]; isWord(c) || c == '.' || c == '-' || c == '+' {
+			return false
+		}
+	}
+	if end < len(s) {
+		c := s[end]
+		if isWord(c) || (c == '.' || c == '-') && end+1 < len(s) && isWord(s[end+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWord(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isSeparator(c byte) bool {
+	return c == ' ' || c == '.' || c == '-'
+}
+
+// sortedByOrder sorts built-in detector names in the order they must run,
+// keeping unknown names so New can report them
+func sortedByOrder(names []string) []string {
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		rank[name] = i
+	}
+	sorted := append([]string(nil), names...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, ok := rank[sorted[i]]
+		if !ok {
+			ri = len(order)
+		}
+		rj, ok := rank[sorted[j]]
+		if !ok {
+			rj = len(order)
+		}
+		return ri < rj
+	})
+	return sorted
+}
+
+// digits counts the digits in s
+func digits(s string) int {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhn validates the checksum of card numbers, so that other long numbers
+// are not mistaken for them
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,98 @@
+package pii
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	redactor, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"mail jo.doe+news@example.co.uk today", "mail [EMAIL] today"},
+		{"card 4111 1111 1111 1111 please", "card [CARD] please"},
+		{"amex 3782 822463 10005", "amex [CARD]"},
+		{"card 4111-1111-1111-1112 fails the checksum", "card 4111-1111-1111-1112 fails the checksum"},
+		{"ssn 123-45-6789", "ssn [SSN]"},
+		{"iban GB82 WEST 1234 5698 7654 32", "iban [IBAN]"},
+		{"call +1 415 555 0100.", "call [PHONE]."},
+		{"call (415) 555-0100 or 030 1234 5678", "call [PHONE] or [PHONE]"},
+		{"london +44 20 7946 0958", "london [PHONE]"},
+		{"ship to 221 Baker Street, apt 2", "ship to [ADDRESS]"},
+
+		// Lists and runs of numbers are redacted one by one
+		{"numbers 415-555-0100,415-555-0101", "numbers [PHONE],[PHONE]"},
+		{"call 555 123 4567 555 123 4568", "call [PHONE] [PHONE]"},
+		{"+1 415 555 0100 +1 415 555 0101", "[PHONE] [PHONE]"},
+		{"+1 415 555 0100 415 555 0101", "[PHONE] [PHONE]"},
+		{"cards 4111 1111 1111 1111,5500-0000-0000-0004", "cards [CARD],[CARD]"},
+		{"cards 4111111111111111,4111111111111111", "cards [CARD],[CARD]"},
+		{"cards 4111 1111 1111 1111 5500 0000 0000 0004", "cards [CARD] [CARD]"},
+
+		// Numbers that are not personal data are kept
+		{"host 192.168.100.200 is up", "host 192.168.100.200 is up"},
+		{"host 10.20.30.40", "host 10.20.30.40"},
+		{"due 18.10.2026", "due 18.10.2026"},
+		{"at 2026-10-18 19:43:32", "at 2026-10-18 19:43:32"},
+		{"took 1234 5678 ms", "took 1234 5678 ms"},
+		{"tokens 1200 300 4000", "tokens 1200 300 4000"},
+		{"order 12 34 56 78", "order 12 34 56 78"},
+		{"id 12345678-1234-5678-1234-567812345678", "id 12345678-1234-5678-1234-567812345678"},
+		{"version 1.22.4", "version 1.22.4"},
+		{"order 4111111111111111x", "order 4111111111111111x"},
+	}
+	for _, test := range tests {
+		if got := redactor.Redact(test.in); got != test.want {
+			t.Errorf("Redact(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	redactor, err := New(Config{
+		Detectors: []string{"email"},
+		Custom:    map[string]string{"account": `ACC-\d{8}`, "pin": `pin (?P<value>\d{4})`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := redactor.Redact("ACC-12345678, pin 1234, jo@example.com, 4111 1111 1111 1111")
+	want := "[ACCOUNT], pin [PIN], [EMAIL], 4111 1111 1111 1111"
+	if got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+
+	if _, err := New(Config{Detectors: []string{"passport"}}); err == nil {
+		t.Error("New accepted an unknown detector")
+	}
+	if _, err := New(Config{Custom: map[string]string{"bad": `(`}}); err == nil {
+		t.Error("New accepted an invalid pattern")
+	}
+
+	var nilRedactor *Redactor
+	if got := nilRedactor.Redact("jo@example.com"); got != "jo@example.com" {
+		t.Errorf("nil Redactor changed the text to %q", got)
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		valid  bool
+	}{
+		{"4111111111111111", true},
+		{"4111 1111 1111 1111", true},
+		{"5500-0000-0000-0004", true},
+		{"378282246310005", true},
+		{"4111111111111112", false},
+		{"1234567812345678", false},
+		{"79927398713", true},
+		{"79927398710", false},
+	}
+	for _, test := range tests {
+		if got := luhn(test.number); got != test.valid {
+			t.Errorf("luhn(%q) = %v, want %v", test.number, got, test.valid)
+		}
+	}
+}
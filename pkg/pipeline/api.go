@@ -0,0 +1,224 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"realtime/pkg/audioformat"
+	"strings"
+)
+
+// transcribe sends a segment of audio to the transcription endpoint
+func (c *PipelineClient) transcribe(ctx context.Context, samples []int16) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", c.config.TranscriptionModel)
+	file, err := form.CreateFormFile("file", "speech.wav")
+	if err != nil {
+		return "", err
+	}
+	file.Write(encodeWAV(samples, sampleRate))
+	form.Close()
+
+	req, err := c.newRequest(ctx, c.config.TranscriptionBaseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("error transcribing audio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var transcription transcriptionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&transcription); err != nil {
+		return "", fmt.Errorf("error decoding transcription: %w", err)
+	}
+	return strings.TrimSpace(transcription.Text), nil
+}
+
+// streamChat streams a chat completion, calling onContent with each piece of
+// content. It returns the tool calls the model made, if any.
+func (c *PipelineClient) streamChat(ctx context.Context, messages []chatMessage, onContent func(string)) ([]chatToolCall, error) {
+	payload, err := json.Marshal(chatRequest{
+		Model:    c.config.ChatModel,
+		Messages: messages,
+		Tools:    c.chatTools(),
+		Stream:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling chat request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, c.config.ChatBaseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error creating chat completion: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var toolCalls []chatToolCall
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("error decoding chat chunk: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				onContent(choice.Delta.Content)
+			}
+			// Tool calls are streamed in pieces, identified by their index
+			for _, delta := range choice.Delta.ToolCalls {
+				for len(toolCalls) <= delta.Index {
+					toolCalls = append(toolCalls, chatToolCall{Type: "function"})
+				}
+				call := &toolCalls[delta.Index]
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				call.Function.Name += delta.Function.Name
+				call.Function.Arguments += delta.Function.Arguments
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading chat stream: %w", err)
+	}
+
+	return toolCalls, nil
+}
+
+// speak converts text to 24kHz PCM16 audio with the speech endpoint
+func (c *PipelineClient) speak(ctx context.Context, text string) ([]byte, error) {
+	payload, err := json.Marshal(speechRequest{
+		Model:          c.config.SpeechModel,
+		Input:          text,
+		Voice:          c.config.Voice,
+		ResponseFormat: "pcm",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling speech request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, c.config.SpeechBaseURL+"/audio/speech", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error synthesizing speech: %w", err)
+	}
+	defer resp.Body.Close()
+
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading speech: %w", err)
+	}
+
+	// Some local servers ignore response_format and always return WAV
+	if data, rate, ok := parseWAV(audio); ok {
+		samples, err := audioformat.Decode(audioformat.PCM16, data[:len(data)/2*2])
+		if err != nil {
+			return nil, err
+		}
+		return audioformat.Encode(audioformat.PCM16, audioformat.Resample(samples, rate, sampleRate)), nil
+	}
+	return audio, nil
+}
+
+func (c *PipelineClient) newRequest(ctx context.Context, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+	return req, nil
+}
+
+// do sends a request and returns an error for non-2xx responses
+func (c *PipelineClient) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// encodeWAV wraps mono PCM16 samples in a WAV header
+func encodeWAV(samples []int16, sampleRate int) []byte {
+	dataSize := uint32(len(samples) * 2)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))           // fmt chunk size
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // mono
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))   // sample rate
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2)) // byte rate
+	binary.Write(&buf, binary.LittleEndian, uint16(2))            // block align
+	binary.Write(&buf, binary.LittleEndian, uint16(16))           // bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// parseWAV returns the PCM16 data and sample rate of a WAV file, ok is false if the audio is not WAV
+func parseWAV(audio []byte) (data []byte, sampleRate int, ok bool) {
+	if len(audio) < 12 || string(audio[0:4]) != "RIFF" || string(audio[8:12]) != "WAVE" {
+		return nil, 0, false
+	}
+	for offset := 12; offset+8 <= len(audio); {
+		id := string(audio[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(audio[offset+4 : offset+8]))
+		body := audio[offset+8:]
+		if size < len(body) {
+			body = body[:size]
+		}
+		switch id {
+		case "fmt ":
+			if len(body) >= 8 {
+				sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			}
+		case "data":
+			return body, sampleRate, true
+		}
+		offset += 8 + size + size%2
+	}
+	return nil, sampleRate, true
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"log/slog"
+	"realtime/pkg/approval"
+	"realtime/pkg/pii"
+)
+
+type Config struct {
+	BaseURL string // OpenAI-compatible API base URL, e.g. http://localhost:8080/v1
+	APIKey  string // Sent as a bearer token if set
+
+	// Each stage can use a different server, defaulting to BaseURL
+	TranscriptionBaseURL string // e.g. a whisper.cpp server
+	ChatBaseURL          string // e.g. a llama.cpp server
+	SpeechBaseURL        string // e.g. a local TTS server
+
+	TranscriptionModel string // Defaults to whisper-1
+	ChatModel          string // Defaults to gpt-4o-mini
+	SpeechModel        string // Defaults to tts-1
+	Voice              string // Defaults to alloy
+	Instructions       string // System prompt of the chat model
+
+	VAD      VADConfig
+	Logger   *slog.Logger   // Defaults to a charm logger on stderr, secrets are always redacted
+	Approval *approval.Gate // Decides on calls of tools that require confirmation, which are rejected without it
+	ModelPII *pii.Redactor  // Redacts personal data in transcripts and tool outputs sent to the chat model, optional
+}
+
+// VADConfig tunes the local voice activity detection that segments mic audio
+type VADConfig struct {
+	Threshold    float64 // RMS level (0-1) above which audio is speech, defaults to 0.02
+	SilenceMS    int     // Silence that ends a segment, defaults to 700
+	PrefixMS     int     // Audio kept from before speech was detected, defaults to 300
+	MinSpeechMS  int     // Shorter segments are discarded as noise, defaults to 250
+	MaxSegmentMS int     // Segments are cut off at this length, defaults to 30000
+}
+
+// chatMessage is a message of the chat completions API
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content,omitempty"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+type chatToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Tools    []chatTool    `json:"tools,omitempty"`
+	Stream   bool          `json:"stream"`
+}
+
+// chatToolCallDelta is a piece of a streamed tool call, identified by its index
+type chatToolCallDelta struct {
+	chatToolCall
+	Index int `json:"index"`
+}
+
+// chatChunk is a streamed chat completion chunk
+type chatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string              `json:"content"`
+			ToolCalls []chatToolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+type transcriptionResponse struct {
+	Text string `json:"text"`
+}
+
+type speechRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
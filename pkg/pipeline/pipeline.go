@@ -0,0 +1,437 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"realtime/pkg/audioformat"
+	"realtime/pkg/logging"
+	"realtime/pkg/metrics"
+	"realtime/pkg/provider"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attached audio is 24kHz PCM16 in both directions, like the realtime backends
+const sampleRate = 24000
+
+// metricsBackend is the backend label of the client's Prometheus metrics
+const metricsBackend = "pipeline"
+
+var _ provider.RealtimeProvider = (*PipelineClient)(nil)
+
+// PipelineClient is a cascaded backend for OpenAI-compatible servers without
+// a realtime endpoint. Mic audio is segmented by a local VAD, each segment is
+// transcribed, the transcript is answered by a streamed chat completion and
+// each sentence of the answer is synthesized to speech.
+type PipelineClient struct {
+	config        Config
+	logger        *slog.Logger
+	httpClient    *http.Client
+	audioOutput   chan<- provider.AudioChunk
+	audioInput    <-chan []byte
+	tools         map[string]provider.Tool
+	toolsMu       sync.Mutex
+	eventHandlers []provider.EventHandler
+	handlersMu    sync.Mutex
+	messages      []chatMessage // Conversation history, only used by processTurns
+	turns         chan turn
+	responding    atomic.Bool
+	playbackUntil atomic.Int64 // Unix nanoseconds when queued speech finishes playing
+	started       atomic.Bool
+	done          chan struct{}
+	closeOnce     sync.Once
+	ctx           context.Context // Cancelled when the client is closed
+	cancel        context.CancelFunc
+}
+
+// turn is a user turn, either a segment of speech or a text message
+type turn struct {
+	audio []int16
+	text  string
+}
+
+// NewPipelineClient initializes a new pipeline client
+func NewPipelineClient(config Config) (*PipelineClient, error) {
+	if config.BaseURL == "" && (config.TranscriptionBaseURL == "" || config.ChatBaseURL == "" || config.SpeechBaseURL == "") {
+		return nil, errors.New("base URL is not set")
+	}
+	if config.TranscriptionBaseURL == "" {
+		config.TranscriptionBaseURL = config.BaseURL
+	}
+	if config.ChatBaseURL == "" {
+		config.ChatBaseURL = config.BaseURL
+	}
+	if config.SpeechBaseURL == "" {
+		config.SpeechBaseURL = config.BaseURL
+	}
+	config.TranscriptionBaseURL = strings.TrimSuffix(config.TranscriptionBaseURL, "/")
+	config.ChatBaseURL = strings.TrimSuffix(config.ChatBaseURL, "/")
+	config.SpeechBaseURL = strings.TrimSuffix(config.SpeechBaseURL, "/")
+
+	if config.TranscriptionModel == "" {
+		config.TranscriptionModel = "whisper-1"
+	}
+	if config.ChatModel == "" {
+		config.ChatModel = "gpt-4o-mini"
+	}
+	if config.SpeechModel == "" {
+		config.SpeechModel = "tts-1"
+	}
+	if config.Voice == "" {
+		config.Voice = "alloy"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := &PipelineClient{
+		config:     config,
+		logger:     logging.OrDefault(config.Logger),
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		tools:      make(map[string]provider.Tool),
+		turns:      make(chan turn, 8),
+		done:       make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	if config.Instructions != "" {
+		client.messages = append(client.messages, chatMessage{Role: "system", Content: config.Instructions})
+	}
+
+	return client, nil
+}
+
+// Connect is a no-op, each stage makes its own HTTP requests
+func (c *PipelineClient) Connect(ctx context.Context) error {
+	return nil
+}
+
+// AttachAudioOutput attaches an audio output channel for assistant -> client communication
+func (c *PipelineClient) AttachAudioOutput(output chan<- provider.AudioChunk) {
+	c.audioOutput = output
+}
+
+// AttachAudioInput attaches an audio input channel for client -> assistant communication
+func (c *PipelineClient) AttachAudioInput(input <-chan []byte) {
+	c.audioInput = input
+}
+
+// OnEvent registers a handler for events from the assistant
+func (c *PipelineClient) OnEvent(handler provider.EventHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.eventHandlers = append(c.eventHandlers, handler)
+}
+
+// RegisterTool declares a tool the chat model can call
+func (c *PipelineClient) RegisterTool(tool provider.Tool) error {
+	if tool.Name == "" {
+		return errors.New("tool name is required")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	if _, ok := c.tools[tool.Name]; ok {
+		return fmt.Errorf("tool %s is already registered", tool.Name)
+	}
+	c.tools[tool.Name] = tool
+	return nil
+}
+
+// Start processes audio until the client is closed
+func (c *PipelineClient) Start() error {
+	if c.audioOutput == nil {
+		return errors.New("audio output channel is not attached")
+	}
+	if c.audioInput == nil {
+		return errors.New("audio input channel is not attached")
+	}
+
+	c.started.Store(true)
+	go c.processTurns()
+	go c.listenForAudioInput()
+
+	<-c.done
+	return nil
+}
+
+// SendText sends a text message from the user and asks the assistant to respond
+func (c *PipelineClient) SendText(text string) error {
+	select {
+	case c.turns <- turn{text: text}:
+		return nil
+	case <-c.done:
+		return errors.New("client is closed")
+	}
+}
+
+// Done returns a channel that is closed when the client is closed
+func (c *PipelineClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close stops the client and cancels any request in progress
+func (c *PipelineClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cancel()
+	})
+	return nil
+}
+
+// listenForAudioInput segments mic audio into turns. Audio is discarded while
+// the assistant is responding, so it does not hear itself.
+func (c *PipelineClient) listenForAudioInput() {
+	segmenter := newSegmenter(c.config.VAD, sampleRate)
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case audio, ok := <-c.audioInput:
+			if !ok {
+				return
+			}
+			if c.responding.Load() || time.Now().UnixNano() < c.playbackUntil.Load() {
+				continue
+			}
+
+			samples, err := audioformat.Decode(audioformat.PCM16, audio)
+			if err != nil {
+				c.logger.Error("Error decoding audio input", "error", err)
+				continue
+			}
+
+			event, segment := segmenter.add(samples)
+			switch event {
+			case speechStarted:
+				c.emit(provider.Event{Type: provider.SpeechStarted})
+			case speechStopped:
+				c.emit(provider.Event{Type: provider.SpeechStopped})
+				if segment != nil {
+					c.turns <- turn{audio: segment}
+				}
+			}
+		}
+	}
+}
+
+func (c *PipelineClient) processTurns() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case t := <-c.turns:
+			text := t.text
+			if t.audio != nil {
+				transcript, err := c.transcribe(c.ctx, t.audio)
+				if err != nil {
+					c.logger.Error("Transcription failed", "error", err)
+					metrics.Errors.WithLabelValues(metricsBackend, "transcription_failed").Inc()
+					c.emit(provider.Event{Type: provider.Error, Text: err.Error()})
+					continue
+				}
+				if transcript == "" {
+					continue
+				}
+				c.emit(provider.Event{Type: provider.InputTranscript, Text: transcript})
+				text = transcript
+			}
+
+			c.respond(text)
+		}
+	}
+}
+
+// respond answers a user message, speaking each sentence as soon as it has been generated
+func (c *PipelineClient) respond(text string) {
+	c.responding.Store(true)
+	defer c.responding.Store(false)
+
+	responseID := uuid.NewString()
+	c.emit(provider.Event{Type: provider.ResponseStarted, ResponseID: responseID})
+	c.messages = append(c.messages, chatMessage{Role: "user", Content: c.config.ModelPII.Redact(text)})
+
+	// Sentences are synthesized in order while the rest of the answer streams in
+	sentences := make(chan string, 16)
+	spoken := make(chan struct{})
+	go func() {
+		defer close(spoken)
+		for sentence := range sentences {
+			c.speakSentence(responseID, sentence)
+		}
+	}()
+
+	var transcript strings.Builder
+	for {
+		var content, pending strings.Builder
+		toolCalls, err := c.streamChat(c.ctx, c.messages, func(delta string) {
+			content.WriteString(delta)
+			transcript.WriteString(delta)
+			c.emit(provider.Event{Type: provider.TranscriptDelta, ResponseID: responseID, Text: delta})
+
+			pending.WriteString(delta)
+			complete, rest := splitSentences(pending.String())
+			for _, sentence := range complete {
+				sentences <- sentence
+			}
+			pending.Reset()
+			pending.WriteString(rest)
+		})
+		if rest := strings.TrimSpace(pending.String()); rest != "" {
+			sentences <- rest
+		}
+		if err != nil {
+			c.logger.Error("Chat completion failed", "response_id", responseID, "error", err)
+			metrics.Errors.WithLabelValues(metricsBackend, "chat_failed").Inc()
+			c.emit(provider.Event{Type: provider.Error, ResponseID: responseID, Text: err.Error()})
+			break
+		}
+
+		c.messages = append(c.messages, chatMessage{
+			Role:      "assistant",
+			Content:   content.String(),
+			ToolCalls: toolCalls,
+		})
+		if len(toolCalls) == 0 {
+			break
+		}
+
+		// Run the tools and let the model continue with their outputs
+		for _, call := range toolCalls {
+			c.messages = append(c.messages, chatMessage{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    c.config.ModelPII.Redact(c.callTool(responseID, call)),
+			})
+		}
+	}
+
+	close(sentences)
+	<-spoken
+
+	c.emit(provider.Event{Type: provider.Transcript, ResponseID: responseID, Text: transcript.String()})
+	c.emit(provider.Event{Type: provider.ResponseDone, ResponseID: responseID})
+}
+
+// speakSentence synthesizes a sentence and queues it for playback
+func (c *PipelineClient) speakSentence(responseID, sentence string) {
+	audio, err := c.speak(c.ctx, sentence)
+	if err != nil {
+		c.logger.Error("Speech synthesis failed", "error", err)
+		metrics.Errors.WithLabelValues(metricsBackend, "speech_failed").Inc()
+		c.emit(provider.Event{Type: provider.Error, Text: err.Error()})
+		return
+	}
+
+	// Track when the queued audio will have finished playing
+	duration := time.Duration(len(audio)/2) * time.Second / sampleRate
+	start := time.Now().UnixNano()
+	if until := c.playbackUntil.Load(); until > start {
+		start = until
+	}
+	c.playbackUntil.Store(start + int64(duration))
+
+	metrics.AudioBytes.WithLabelValues(metricsBackend, "out").Add(float64(len(audio)))
+	select {
+	case c.audioOutput <- provider.AudioChunk{ResponseID: responseID, Audio: audio}:
+	case <-c.done:
+	}
+}
+
+// callTool runs a tool the chat model called and returns its output
+func (c *PipelineClient) callTool(responseID string, call chatToolCall) string {
+	arguments := json.RawMessage(call.Function.Arguments)
+	c.emit(provider.Event{
+		Type:       provider.ToolCalled,
+		ResponseID: responseID,
+		ToolCall:   &provider.ToolCall{CallID: call.ID, Name: call.Function.Name, Arguments: arguments},
+	})
+
+	c.toolsMu.Lock()
+	tool, ok := c.tools[call.Function.Name]
+	c.toolsMu.Unlock()
+	if !ok {
+		c.logger.Warn("Assistant called unknown tool", "tool", call.Function.Name, "call_id", call.ID, "response_id", responseID)
+		return fmt.Sprintf(`{"error":"unknown tool %s"}`, call.Function.Name)
+	}
+
+	if tool.RequiresConfirmation {
+		err := c.config.Approval.Authorize(c.ctx, provider.ToolCall{CallID: call.ID, Name: call.Function.Name, Arguments: arguments})
+		if err != nil {
+			errorOutput, _ := json.Marshal(map[string]string{"error": err.Error()})
+			return string(errorOutput)
+		}
+	}
+
+	c.logger.Info("Calling tool", "tool", call.Function.Name, "call_id", call.ID, "response_id", responseID, "arguments", call.Function.Arguments)
+	start := time.Now()
+	output, err := tool.Handler(c.ctx, arguments)
+	metrics.ToolCalled(metricsBackend, call.Function.Name, start, err)
+	if err != nil {
+		c.logger.Error("Tool failed", "tool", call.Function.Name, "call_id", call.ID, "response_id", responseID, "error", err)
+		errorOutput, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(errorOutput)
+	}
+	return output
+}
+
+// chatTools returns the registered tools in the chat completions format
+func (c *PipelineClient) chatTools() []chatTool {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+
+	var tools []chatTool
+	for _, tool := range c.tools {
+		chatTool := chatTool{Type: "function"}
+		chatTool.Function.Name = tool.Name
+		chatTool.Function.Description = tool.Description
+		chatTool.Function.Parameters = tool.Parameters
+		if len(chatTool.Function.Parameters) == 0 {
+			chatTool.Function.Parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		tools = append(tools, chatTool)
+	}
+	return tools
+}
+
+// emit sends an event to all registered handlers
+func (c *PipelineClient) emit(event provider.Event) {
+	c.handlersMu.Lock()
+	handlers := c.eventHandlers
+	c.handlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// splitSentences returns the complete sentences at the start of text and the
+// unfinished rest. A sentence ends with punctuation followed by whitespace.
+func splitSentences(text string) ([]string, string) {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text)-1; i++ {
+		switch text[i] {
+		case '.', '!', '?', '\n':
+			next := text[i+1]
+			if next == ' ' || next == '\n' || next == '\t' {
+				if sentence := strings.TrimSpace(text[start : i+1]); sentence != "" {
+					sentences = append(sentences, sentence)
+				}
+				start = i + 1
+			}
+		}
+	}
+	return sentences, text[start:]
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"realtime/pkg/audioformat"
+	"time"
+)
+
+// segmenter splits a stream of microphone audio into speech segments using
+// the RMS level of each chunk
+type segmenter struct {
+	config     VADConfig
+	sampleRate int
+	prefix     []int16 // Recent audio before speech was detected
+	segment    []int16
+	speaking   bool
+	speechMS   int
+	silenceMS  int
+}
+
+func newSegmenter(config VADConfig, sampleRate int) *segmenter {
+	if config.Threshold <= 0 {
+		config.Threshold = 0.02
+	}
+	if config.SilenceMS <= 0 {
+		config.SilenceMS = 700
+	}
+	if config.PrefixMS <= 0 {
+		config.PrefixMS = 300
+	}
+	if config.MinSpeechMS <= 0 {
+		config.MinSpeechMS = 250
+	}
+	if config.MaxSegmentMS <= 0 {
+		config.MaxSegmentMS = 30000
+	}
+	return &segmenter{config: config, sampleRate: sampleRate}
+}
+
+// segmentEvent reports a change in the segmenter's state
+type segmentEvent int
+
+const (
+	noSegmentEvent segmentEvent = iota
+	speechStarted
+	speechStopped
+)
+
+// add processes a chunk of audio. When a segment ends it is returned along
+// with speechStopped, segments too short to be speech are dropped.
+func (s *segmenter) add(samples []int16) (segmentEvent, []int16) {
+	chunkMS := int(time.Duration(len(samples)) * time.Second / time.Duration(s.sampleRate) / time.Millisecond)
+	loud := audioformat.RMS(samples) > s.config.Threshold
+
+	if !s.speaking {
+		if !loud {
+			s.prefix = append(s.prefix, samples...)
+			if maxPrefix := s.config.PrefixMS * s.sampleRate / 1000; len(s.prefix) > maxPrefix {
+				s.prefix = s.prefix[len(s.prefix)-maxPrefix:]
+			}
+			return noSegmentEvent, nil
+		}
+
+		s.speaking = true
+		s.segment = append(append([]int16{}, s.prefix...), samples...)
+		s.prefix = nil
+		s.speechMS = chunkMS
+		s.silenceMS = 0
+		return speechStarted, nil
+	}
+
+	s.segment = append(s.segment, samples...)
+	s.speechMS += chunkMS
+	if loud {
+		s.silenceMS = 0
+	} else {
+		s.silenceMS += chunkMS
+	}
+
+	if s.silenceMS < s.config.SilenceMS && s.speechMS < s.config.MaxSegmentMS {
+		return noSegmentEvent, nil
+	}
+
+	segment := s.segment
+	spoken := s.speechMS - s.silenceMS
+	s.speaking = false
+	s.segment = nil
+	if spoken < s.config.MinSpeechMS {
+		return speechStopped, nil
+	}
+	return speechStopped, segment
+}
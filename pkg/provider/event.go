@@ -0,0 +1,49 @@
+package provider
+
+// EventType identifies the kind of an Event
+type EventType string
+
+const (
+	// SpeechStarted is sent when the user starts talking
+	SpeechStarted EventType = "speech_started"
+	// SpeechStopped is sent when the user stops talking
+	SpeechStopped EventType = "speech_stopped"
+	// InputTranscriptDelta carries part of the transcript of what the user said, for partial captions
+	InputTranscriptDelta EventType = "input_transcript_delta"
+	// InputTranscript carries the transcript of what the user said
+	InputTranscript EventType = "input_transcript"
+	// InputTranscriptFailed is sent when the user's speech could not be transcribed
+	InputTranscriptFailed EventType = "input_transcript_failed"
+	// ResponseStarted is sent when the assistant starts a response
+	ResponseStarted EventType = "response_started"
+	// TranscriptDelta carries part of the transcript of the assistant's response
+	TranscriptDelta EventType = "transcript_delta"
+	// Transcript carries the full transcript of the assistant's response
+	Transcript EventType = "transcript"
+	// ResponseDone is sent when the assistant finishes a response
+	ResponseDone EventType = "response_done"
+	// ResponseInterrupted is sent when the user interrupts the assistant
+	ResponseInterrupted EventType = "response_interrupted"
+	// ToolCalled is sent when the assistant calls a tool
+	ToolCalled EventType = "tool_called"
+	// Handoff is sent when another agent takes over the conversation, Text is its name
+	Handoff EventType = "handoff"
+	// GuardrailTripped is sent when a transcript breaks a guardrail, Text is the
+	// violation. ResponseID is set for the assistant's output, ItemID for the user's input.
+	GuardrailTripped EventType = "guardrail_tripped"
+	// Error is sent when the backend reports an error
+	Error EventType = "error"
+)
+
+// Event is something that happened in the conversation
+type Event struct {
+	Type       EventType
+	ResponseID string
+	ItemID     string
+	Text       string    // Transcript text, or error message for Error and InputTranscriptFailed
+	ToolCall   *ToolCall // Set for ToolCalled
+}
+
+// EventHandler handles events from the assistant. Handlers are called in
+// order from the provider's event loop and must not block.
+type EventHandler func(event Event)
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RealtimeProvider is a speech-to-speech backend. Audio is exchanged as
+// chunks on channels, so the audio and UI code does not depend on the backend.
+type RealtimeProvider interface {
+	// Connect connects to the backend
+	Connect(ctx context.Context) error
+	// AttachAudioInput attaches a channel of microphone audio for the assistant
+	AttachAudioInput(input <-chan []byte)
+	// AttachAudioOutput attaches a channel the assistant's audio is sent to,
+	// in order
+	AttachAudioOutput(output chan<- AudioChunk)
+	// RegisterTool declares a tool the assistant can call
+	RegisterTool(tool Tool) error
+	// OnEvent registers a handler for events from the assistant
+	OnEvent(handler EventHandler)
+	// Start starts the conversation and blocks until the provider is closed
+	Start() error
+	// SendText sends a text message from the user and asks the assistant to respond
+	SendText(text string) error
+	// Close disconnects from the backend
+	Close() error
+	// Done returns a channel that is closed when the provider is closed
+	Done() <-chan struct{}
+}
+
+// AudioChunk is a chunk of the assistant's audio. The IDs tell the output
+// which response and item it belongs to, e.g. to stop playing a response
+// that was interrupted. Backends without item IDs leave ItemID empty.
+type AudioChunk struct {
+	ResponseID   string
+	ItemID       string
+	ContentIndex int
+	Audio        []byte
+}
+
+// ToolHandler runs a tool with the JSON arguments chosen by the assistant and
+// returns the output reported back to it
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// Tool is a function the assistant can call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage // JSON schema of the arguments
+	Handler     ToolHandler
+	// RequiresConfirmation holds calls until a human approves them, for tools
+	// that change things. Calls are rejected if the provider has no approval gate.
+	RequiresConfirmation bool
+	// Async tools are long-running. The assistant is told at once that the
+	// call is in progress and the conversation goes on; the output is handed
+	// to the assistant to announce when the handler returns. Providers
+	// without support run them like other tools.
+	Async bool
+}
+
+// ToolCall is a call of a tool by the assistant
+type ToolCall struct {
+	CallID    string
+	Name      string
+	Arguments json.RawMessage
+}
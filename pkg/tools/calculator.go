@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"realtime/pkg/provider"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func calculatorTool() provider.Tool {
+	return provider.Tool{
+		Name: "calculate",
+		Description: "Evaluate an arithmetic expression exactly instead of estimating. Supports + - * / % ^, " +
+			"parentheses, pi, e and the functions sqrt, abs, round, floor, ceil, ln, log, sin, cos and tan (radians)",
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"expression":{"type":"string","description":"e.g. (17.5 * 12) / 3 or sqrt(2) ^ 2"}},"required":["expression"]}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				Expression string `json:"expression"`
+			}
+			if err := decodeArguments(arguments, &args); err != nil {
+				return "", err
+			}
+			result, err := evaluate(args.Expression)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(result, 'g', 12, 64), nil
+		},
+	}
+}
+
+// thousands matches numbers with thousands separators, e.g. 1,234,567.89
+var thousands = regexp.MustCompile(`^\d{1,3}(?:,\d{3})+(?:\.\d*)?$`)
+
+var functions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"round": math.Round,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// evaluate parses and evaluates an arithmetic expression
+func evaluate(expression string) (float64, error) {
+	p := &parser{input: expression}
+	result, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos:], p.pos+1)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("%s is not a finite number", expression)
+	}
+	return result, nil
+}
+
+// parser is a recursive descent parser of:
+//
+//	expression = term {("+" | "-") term}
+//	term       = unary {("*" | "/" | "%") unary}
+//	unary      = ["-" | "+"] unary | power
+//	power      = primary ["^" unary]
+//	primary    = number | constant | function "(" expression ")" | "(" expression ")"
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// accept consumes the next character if it is one of chars
+func (p *parser) accept(chars string) (byte, bool) {
+	p.skipSpaces()
+	if p.pos < len(p.input) && strings.IndexByte(chars, p.input[p.pos]) >= 0 {
+		p.pos++
+		return p.input[p.pos-1], true
+	}
+	return 0, false
+}
+
+func (p *parser) expression() (float64, error) {
+	left, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.accept("+-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+func (p *parser) term() (float64, error) {
+	left, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.accept("*/%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+func (p *parser) unary() (float64, error) {
+	if op, ok := p.accept("+-"); ok {
+		value, err := p.unary()
+		if op == '-' {
+			value = -value
+		}
+		return value, err
+	}
+	return p.power()
+}
+
+func (p *parser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.accept("^"); ok {
+		exponent, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+	return base, nil
+}
+
+func (p *parser) primary() (float64, error) {
+	if _, ok := p.accept("("); ok {
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return value, nil
+	}
+
+	p.skipSpaces()
+	start := p.pos
+	if p.pos < len(p.input) && unicode.IsLetter(rune(p.input[p.pos])) {
+		for p.pos < len(p.input) && unicode.IsLetter(rune(p.input[p.pos])) {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		if value, ok := constants[name]; ok {
+			return value, nil
+		}
+		function, ok := functions[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown function or constant %q", name)
+		}
+		if _, ok := p.accept("("); !ok {
+			return 0, fmt.Errorf("expected ( after %s", name)
+		}
+		arg, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return function(arg), nil
+	}
+
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.' || p.input[p.pos] == ',') {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos >= len(p.input) {
+			return 0, fmt.Errorf("unexpected end of expression")
+		}
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	// Commas only separate thousands, e.g. 1,000, so 1,5 is not taken for 15
+	number := p.input[start:p.pos]
+	if strings.Contains(number, ",") {
+		if !thousands.MatchString(number) {
+			return 0, fmt.Errorf("invalid number %q", number)
+		}
+		number = strings.ReplaceAll(number, ",", "")
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return value, nil
+}
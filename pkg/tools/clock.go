@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"realtime/pkg/provider"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func clockTool() provider.Tool {
+	return provider.Tool{
+		Name:        "current_time",
+		Description: "Get the current date and time, optionally in another time zone",
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"time_zone":{"type":"string","description":"IANA time zone, e.g. Europe/Paris. Defaults to the user's local time zone"}}}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				TimeZone string `json:"time_zone"`
+			}
+			if err := decodeArguments(arguments, &args); err != nil {
+				return "", err
+			}
+
+			now := time.Now()
+			if args.TimeZone != "" {
+				location, err := time.LoadLocation(args.TimeZone)
+				if err != nil {
+					return "", fmt.Errorf("unknown time zone %q", args.TimeZone)
+				}
+				now = now.In(location)
+			}
+			return now.Format("Monday, January 2, 2006 15:04:05 MST"), nil
+		},
+	}
+}
+
+// timers runs timers the assistant sets, calling onFire when each one goes off
+type timers struct {
+	mu     sync.Mutex
+	timers map[string]*timer
+	onFire func(label string)
+}
+
+type timer struct {
+	t      *time.Timer
+	endsAt time.Time
+}
+
+func newTimers(onFire func(label string)) *timers {
+	return &timers{timers: make(map[string]*timer), onFire: onFire}
+}
+
+func (t *timers) tools() []provider.Tool {
+	return []provider.Tool{
+		{
+			Name:        "set_timer",
+			Description: "Set a timer that goes off after the given duration",
+			Parameters: json.RawMessage(`{"type":"object","properties":{
+				"seconds":{"type":"number","description":"Duration of the timer in seconds"},
+				"label":{"type":"string","description":"Short name of the timer, e.g. pasta"}},
+				"required":["seconds","label"]}`),
+			Handler: t.set,
+		},
+		{
+			Name:        "cancel_timer",
+			Description: "Cancel a timer by its label",
+			Parameters: json.RawMessage(`{"type":"object","properties":{
+				"label":{"type":"string"}},"required":["label"]}`),
+			Handler: t.cancel,
+		},
+		{
+			Name:        "list_timers",
+			Description: "List the running timers and the time left on each",
+			Handler:     t.list,
+		},
+	}
+}
+
+func (t *timers) set(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Seconds float64 `json:"seconds"`
+		Label   string  `json:"label"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	if args.Seconds <= 0 {
+		return "", errors.New("seconds must be positive")
+	}
+	if args.Label == "" {
+		return "", errors.New("label is required")
+	}
+
+	duration := time.Duration(args.Seconds * float64(time.Second))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if existing, ok := t.timers[args.Label]; ok {
+		existing.t.Stop()
+	}
+	entry := &timer{endsAt: time.Now().Add(duration)}
+	entry.t = time.AfterFunc(duration, func() {
+		t.mu.Lock()
+		if t.timers[args.Label] != entry {
+			t.mu.Unlock()
+			return
+		}
+		delete(t.timers, args.Label)
+		t.mu.Unlock()
+		if t.onFire != nil {
+			t.onFire(args.Label)
+		}
+	})
+	t.timers[args.Label] = entry
+	return fmt.Sprintf("Timer %q set for %s", args.Label, duration.Round(time.Second)), nil
+}
+
+func (t *timers) cancel(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Label string `json:"label"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.timers[args.Label]
+	if !ok {
+		return "", fmt.Errorf("no timer called %q", args.Label)
+	}
+	entry.t.Stop()
+	delete(t.timers, args.Label)
+	return fmt.Sprintf("Timer %q cancelled", args.Label), nil
+}
+
+func (t *timers) list(ctx context.Context, arguments json.RawMessage) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.timers) == 0 {
+		return "No timers are running", nil
+	}
+
+	lines := make([]string, 0, len(t.timers))
+	for label, entry := range t.timers {
+		lines = append(lines, fmt.Sprintf("%s: %s left", label, time.Until(entry.endsAt).Round(time.Second)))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"realtime/pkg/provider"
+	"strings"
+)
+
+// files reads files under an allowlisted directory
+type files struct {
+	root     string // Absolute path with symlinks resolved
+	maxBytes int
+}
+
+func newFiles(dir string, maxBytes int) (*files, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid files directory: %w", err)
+	}
+	return &files{root: root, maxBytes: maxBytes}, nil
+}
+
+func (f *files) tools() []provider.Tool {
+	return []provider.Tool{
+		{
+			Name:        "read_file",
+			Description: "Read a text file from the user's shared directory",
+			Parameters: json.RawMessage(`{"type":"object","properties":{
+				"path":{"type":"string","description":"Path relative to the shared directory"}},"required":["path"]}`),
+			Handler: f.read,
+		},
+		{
+			Name:        "list_files",
+			Description: "List the files in a directory of the user's shared directory",
+			Parameters: json.RawMessage(`{"type":"object","properties":{
+				"path":{"type":"string","description":"Path relative to the shared directory, defaults to its root"}}}`),
+			Handler: f.list,
+		},
+	}
+}
+
+// resolve returns the absolute path of a path relative to the root, refusing
+// paths that escape it, including through symlinks
+func (f *files) resolve(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(f.root, filepath.FromSlash(path)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s does not exist", path)
+		}
+		return "", err
+	}
+	rel, err := filepath.Rel(f.root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the shared directory", path)
+	}
+	return resolved, nil
+}
+
+func (f *files) read(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	path, err := f.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", args.Path)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(f.maxBytes)))
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > int64(len(data)) {
+		return fmt.Sprintf("%s\n[truncated, %d of %d bytes shown]", data, len(data), info.Size()), nil
+	}
+	return string(data), nil
+}
+
+func (f *files) list(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	path, err := f.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "The directory is empty", nil
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+		if entry.IsDir() {
+			names[i] += "/"
+		}
+	}
+	return strings.Join(names, "\n"), nil
+}
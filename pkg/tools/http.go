@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"realtime/pkg/provider"
+	"sort"
+	"strings"
+)
+
+// httpGetter fetches URLs on allowlisted hosts
+type httpGetter struct {
+	hosts      []string
+	httpClient *http.Client
+	maxOutput  int
+}
+
+func newHTTPGetter(config Config) *httpGetter {
+	g := &httpGetter{hosts: config.HTTPHosts, maxOutput: config.MaxOutputBytes}
+	g.httpClient = &http.Client{
+		Timeout: config.HTTPTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return g.check(req.URL)
+		},
+	}
+	return g
+}
+
+func (g *httpGetter) tool() provider.Tool {
+	return provider.Tool{
+		Name:        "http_get",
+		Description: fmt.Sprintf("Fetch a URL with HTTP GET. Only these hosts are allowed: %s", strings.Join(g.hosts, ", ")),
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"url":{"type":"string","description":"http or https URL"}},"required":["url"]}`),
+		Handler: g.get,
+	}
+}
+
+// check allows URLs on an allowlisted host or its subdomains
+func (g *httpGetter) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range g.hosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not an allowed host", host)
+}
+
+func (g *httpGetter) get(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	u, err := url.Parse(args.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := g.check(u); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(g.maxOutput)+1))
+	if err != nil {
+		return "", err
+	}
+	output := string(body)
+	if len(body) > g.maxOutput {
+		output = fmt.Sprintf("%s\n[truncated after %d bytes]", body[:g.maxOutput], g.maxOutput)
+	}
+	return fmt.Sprintf("HTTP %s (%s)\n%s", resp.Status, resp.Header.Get("Content-Type"), output), nil
+}
+
+func sortedStrings(s []string) []string {
+	sort.Strings(s)
+	return s
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"realtime/pkg/provider"
+	"strings"
+	"time"
+)
+
+// waitDelay is how long a command's output is still read after it is killed
+// or exits, e.g. while a child process it started holds the pipe open
+const waitDelay = time.Second
+
+// shell runs allowlisted programs. Commands are run directly, not through a
+// shell, so arguments cannot chain other commands.
+type shell struct {
+	commands  map[string]bool
+	dir       string
+	timeout   time.Duration
+	maxOutput int
+}
+
+func newShell(config Config) *shell {
+	commands := make(map[string]bool, len(config.ShellCommands))
+	for _, command := range config.ShellCommands {
+		commands[command] = true
+	}
+	return &shell{
+		commands:  commands,
+		dir:       config.ShellDir,
+		timeout:   config.ShellTimeout,
+		maxOutput: config.MaxOutputBytes,
+	}
+}
+
+func (s *shell) tool() provider.Tool {
+	allowed := make([]string, 0, len(s.commands))
+	for command := range s.commands {
+		allowed = append(allowed, command)
+	}
+	return provider.Tool{
+		Name: "run_command",
+		Description: fmt.Sprintf("Run a command on the user's machine and get its output. Only these programs are allowed: %s. "+
+			"Commands are killed after %s", strings.Join(sortedStrings(allowed), ", "), s.timeout),
+		Parameters: json.RawMessage(`{"type":"object","properties":{
+			"program":{"type":"string","description":"Name of the program, e.g. git"},
+			"args":{"type":"array","items":{"type":"string"},"description":"Arguments, e.g. [\"status\", \"--short\"]"}},
+			"required":["program"]}`),
+		Handler:              s.run,
+		RequiresConfirmation: true,
+	}
+}
+
+func (s *shell) run(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Program string   `json:"program"`
+		Args    []string `json:"args"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	if !s.commands[args.Program] || filepath.Base(args.Program) != args.Program {
+		return "", fmt.Errorf("%s is not an allowed program", args.Program)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args.Program, args.Args...)
+	cmd.Dir = s.dir
+	// Children that keep the output pipe open must not hold up Run past the timeout
+	cmd.WaitDelay = waitDelay
+	output := &limitedBuffer{max: s.maxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	result := output.String()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("command timed out after %s, output so far:\n%s", s.timeout, result)
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return fmt.Sprintf("%s\n[output cut off, a process the command started is still running]", result), nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Sprintf("%s\n[exit status %d]", result, exitErr.ExitCode()), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"realtime/pkg/provider"
+	"time"
+)
+
+// Defaults for the limits of the tools, used when the config leaves them zero
+const (
+	defaultMaxFileBytes   = 64 * 1024
+	defaultMaxOutputBytes = 16 * 1024
+	defaultShellTimeout   = 10 * time.Second
+	defaultHTTPTimeout    = 10 * time.Second
+)
+
+// Config turns on the built-in tools. Tools that touch the machine or the
+// network only reach what is allowlisted.
+type Config struct {
+	Clock      bool               // current_time
+	Timers     bool               // set_timer, cancel_timer and list_timers
+	OnTimer    func(label string) // Called when a timer fires, e.g. to have the assistant announce it
+	Calculator bool               // calculate
+
+	FilesDir     string // read_file and list_files under this directory, empty to disable
+	MaxFileBytes int    // Longer files are truncated, defaults to 64KiB
+
+	ShellCommands  []string      // run_command for these programs, empty to disable
+	ShellDir       string        // Working directory of commands, defaults to the current directory
+	ShellTimeout   time.Duration // Commands are killed after this long, defaults to 10s
+	MaxOutputBytes int           // Command and HTTP outputs are truncated, defaults to 16KiB
+
+	HTTPHosts   []string      // http_get against these hosts, empty to disable
+	HTTPTimeout time.Duration // Defaults to 10s
+}
+
+// New returns the tools turned on in the config, ready to register with any RealtimeProvider
+func New(config Config) ([]provider.Tool, error) {
+	if config.MaxFileBytes <= 0 {
+		config.MaxFileBytes = defaultMaxFileBytes
+	}
+	if config.MaxOutputBytes <= 0 {
+		config.MaxOutputBytes = defaultMaxOutputBytes
+	}
+	if config.ShellTimeout <= 0 {
+		config.ShellTimeout = defaultShellTimeout
+	}
+	if config.HTTPTimeout <= 0 {
+		config.HTTPTimeout = defaultHTTPTimeout
+	}
+
+	var tools []provider.Tool
+	if config.Clock {
+		tools = append(tools, clockTool())
+	}
+	if config.Timers {
+		tools = append(tools, newTimers(config.OnTimer).tools()...)
+	}
+	if config.Calculator {
+		tools = append(tools, calculatorTool())
+	}
+	if config.FilesDir != "" {
+		files, err := newFiles(config.FilesDir, config.MaxFileBytes)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, files.tools()...)
+	}
+	if len(config.ShellCommands) > 0 {
+		tools = append(tools, newShell(config).tool())
+	}
+	if len(config.HTTPHosts) > 0 {
+		tools = append(tools, newHTTPGetter(config).tool())
+	}
+	return tools, nil
+}
+
+// decodeArguments unmarshals tool arguments, with an error the assistant can explain
+func decodeArguments(arguments json.RawMessage, v interface{}) error {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(arguments, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first max bytes written to it and counts the rest,
+// so a command's output cannot fill memory
+type limitedBuffer struct {
+	buffer bytes.Buffer
+	max    int
+	total  int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.max - b.buffer.Len(); room > 0 {
+		b.buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// String returns the output kept, noting how much was cut
+func (b *limitedBuffer) String() string {
+	if b.total <= b.max {
+		return b.buffer.String()
+	}
+	return fmt.Sprintf("%s\n[truncated, %d of %d bytes shown]", b.buffer.Bytes(), b.max, b.total)
+}